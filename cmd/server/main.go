@@ -34,14 +34,28 @@ func main() {
 
 	// Initialize MCP server
 	mcpServer := mcp.NewServer(spotifyClient, log)
+	if err := mcpServer.LoadPrompts(cfg.Server.PromptsFile); err != nil {
+		log.Fatalf("Failed to load prompts: %v", err)
+	}
+	if err := mcpServer.ConfigureTools(cfg.Tools); err != nil {
+		log.Fatalf("Invalid tools configuration: %v", err)
+	}
+	if err := mcpServer.OpenJournal(cfg.Tools.Journal); err != nil {
+		log.Fatalf("Failed to open change journal: %v", err)
+	}
 
 	// Initialize HTTP handlers
 	handler := handlers.NewHandler(mcpServer, log)
+	auth, err := handlers.NewAuthenticator(mcpServer, cfg.Server.Auth, cfg.Tools.APIKeys, log)
+	if err != nil {
+		log.Fatalf("Invalid auth configuration: %v", err)
+	}
 
 	// Setup HTTP server
 	mux := http.NewServeMux()
-	mux.HandleFunc("/mcp", handler.HandleMCP)
+	mux.Handle("/mcp", auth.Middleware(http.HandlerFunc(handler.HandleMCP)))
 	mux.HandleFunc("/health", handler.HandleHealth)
+	mux.HandleFunc(handlers.ResourceMetadataPath, auth.HandleResourceMetadata)
 
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
@@ -50,6 +64,13 @@ func main() {
 		WriteTimeout: time.Duration(cfg.Server.WriteTimeout) * time.Second,
 	}
 
+	server.RegisterOnShutdown(mcpServer.CloseAllSessions)
+
+	background, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	go mcpServer.RunKeepalive(background, cfg.Server.Keepalive)
+	go auth.RunReload(background, time.Duration(cfg.Server.Auth.ReloadInterval)*time.Second)
+
 	// Start server in a goroutine
 	go func() {
 		log.Infof("Starting MCP server on port %d", cfg.Server.Port)
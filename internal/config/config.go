@@ -11,18 +11,134 @@ type Config struct {
 	Server  ServerConfig  `mapstructure:"server"`
 	Spotify SpotifyConfig `mapstructure:"spotify"`
 	Logging LoggingConfig `mapstructure:"logging"`
+	Tools   ToolsConfig   `mapstructure:"tools"`
 }
 
 type ServerConfig struct {
 	Port         int `mapstructure:"port"`
 	ReadTimeout  int `mapstructure:"read_timeout"`
 	WriteTimeout int `mapstructure:"write_timeout"`
+	// PromptsFile is a YAML file of additional MCP prompts; it is optional.
+	PromptsFile string          `mapstructure:"prompts_file"`
+	Keepalive   KeepaliveConfig `mapstructure:"keepalive"`
+	Auth        AuthConfig      `mapstructure:"auth"`
+}
+
+// AuthConfig controls API key authentication of /mcp, which is required
+// once tools.api_keys lists a key or KeysFile is set. KeysFile holds more
+// api_keys and is reread every ReloadInterval seconds when it changes, so
+// keys can be rotated without a restart. ResourceURL is the public URL of
+// /mcp advertised to clients; by default it is derived from the request.
+type AuthConfig struct {
+	KeysFile       string `mapstructure:"keys_file"`
+	ReloadInterval int    `mapstructure:"reload_interval"`
+	ResourceURL    string `mapstructure:"resource_url"`
+}
+
+// KeepaliveConfig controls how MCP sessions are kept alive. Sessions with
+// an open stream are pinged after Interval seconds without client traffic
+// and closed if they don't answer within Timeout; sessions without a
+// stream are closed after IdleTimeout. An Interval of 0 disables both.
+type KeepaliveConfig struct {
+	Interval    int `mapstructure:"interval"`
+	Timeout     int `mapstructure:"timeout"`
+	IdleTimeout int `mapstructure:"idle_timeout"`
 }
 
 type SpotifyConfig struct {
 	ClientID     string `mapstructure:"client_id"`
 	ClientSecret string `mapstructure:"client_secret"`
 	RedirectURI  string `mapstructure:"redirect_uri"`
+	// RefreshToken enables user-authorized endpoints such as /me. When
+	// empty the client falls back to app-only client credentials.
+	RefreshToken string `mapstructure:"refresh_token"`
+	// DefaultMarket is the ISO 3166-1 alpha-2 country used when a tool call
+	// doesn't specify one. "from_token" uses the authorized user's country.
+	DefaultMarket string `mapstructure:"default_market"`
+	// MaxRetries and MaxRetryWait (seconds) bound retries of rate-limited
+	// and failed requests. A MaxRetries of 0 disables retries.
+	MaxRetries   int         `mapstructure:"max_retries"`
+	MaxRetryWait int         `mapstructure:"max_retry_wait"`
+	Cache        CacheConfig `mapstructure:"cache"`
+	// BatchWindowMs, when positive, merges concurrent get_track lookups
+	// arriving within the window into one multi-ID request.
+	BatchWindowMs int             `mapstructure:"batch_window_ms"`
+	Breaker       BreakerConfig   `mapstructure:"breaker"`
+	Recording     RecordingConfig `mapstructure:"recording"`
+	// APIBaseURL and AccountsBaseURL override the Web API and Accounts
+	// service roots, e.g. to reach a local stand-in or a gateway.
+	APIBaseURL      string `mapstructure:"api_base_url"`
+	AccountsBaseURL string `mapstructure:"accounts_base_url"`
+	// ProxyURL routes all Spotify traffic through an HTTP proxy; when empty
+	// HTTPS_PROXY/NO_PROXY apply. CAFile adds a PEM bundle to the trusted
+	// roots, e.g. for a TLS-intercepting proxy.
+	ProxyURL string `mapstructure:"proxy_url"`
+	CAFile   string `mapstructure:"ca_file"`
+}
+
+// RecordingConfig captures Spotify HTTP traffic to a cassette file, or
+// serves it back from one without network access. Mode is "off",
+// "record" or "replay"; credentials are stripped before writing.
+type RecordingConfig struct {
+	Mode     string `mapstructure:"mode"`
+	Cassette string `mapstructure:"cassette"`
+}
+
+// BreakerConfig controls the circuit breaker that fails fast while Spotify
+// is down. Cooldown is in seconds.
+type BreakerConfig struct {
+	FailureThreshold int `mapstructure:"failure_threshold"`
+	Cooldown         int `mapstructure:"cooldown"`
+}
+
+// CacheConfig controls caching of Spotify GET responses. TTLs override the
+// built-in lifetime (in seconds) per endpoint, e.g. "tracks" or "search";
+// a TTL of 0 disables caching for that endpoint.
+type CacheConfig struct {
+	Enabled   bool           `mapstructure:"enabled"`
+	Backend   string         `mapstructure:"backend"`
+	Dir       string         `mapstructure:"dir"`
+	MaxSizeMB int            `mapstructure:"max_size_mb"`
+	TTLs      map[string]int `mapstructure:"ttls"`
+}
+
+// ToolsConfig selects the MCP tools the server exposes. A tool is enabled
+// when its group is listed in Groups or its name in Enable, unless it is
+// listed in Disable. APIKeys further restrict what each client may use.
+type ToolsConfig struct {
+	Groups  []string       `mapstructure:"groups"`
+	Enable  []string       `mapstructure:"enable"`
+	Disable []string       `mapstructure:"disable"`
+	APIKeys []APIKeyConfig `mapstructure:"api_keys"`
+	// ConfirmDestructive makes destructive tools ask the user to confirm
+	// through MCP elicitation before changing anything.
+	ConfirmDestructive bool `mapstructure:"confirm_destructive"`
+	// ElicitationFallback applies when the client can't be asked:
+	// "dry_run" describes the change without applying it, "reject" fails
+	// the call and "allow" applies the change unconfirmed.
+	ElicitationFallback string        `mapstructure:"elicitation_fallback"`
+	Journal             JournalConfig `mapstructure:"journal"`
+}
+
+// JournalConfig controls the journal of applied changes that undo_change
+// reverts. It is kept in Path, or only in memory when Path is empty, and
+// holds at most MaxEntries changes.
+type JournalConfig struct {
+	Path       string `mapstructure:"path"`
+	MaxEntries int    `mapstructure:"max_entries"`
+}
+
+// APIKeyConfig is a key clients present as a bearer token. They may use
+// enabled tools in Groups, the key's scopes, or named in Tools, and nothing
+// else. The key is given as Hash, "sha256:" and its hex SHA-256 digest, or
+// in plain text as Key. ExpiresAt, an RFC 3339 time, ends its validity.
+type APIKeyConfig struct {
+	Name      string   `mapstructure:"name"`
+	Key       string   `mapstructure:"key"`
+	Hash      string   `mapstructure:"hash"`
+	Groups    []string `mapstructure:"groups"`
+	Tools     []string `mapstructure:"tools"`
+	ExpiresAt string   `mapstructure:"expires_at"`
 }
 
 type LoggingConfig struct {
@@ -42,12 +158,50 @@ func Load() (*Config, error) {
 	viper.BindEnv("spotify.client_id", "SPOTIFY_CLIENT_ID")
 	viper.BindEnv("spotify.client_secret", "SPOTIFY_CLIENT_SECRET")
 	viper.BindEnv("spotify.redirect_uri", "SPOTIFY_REDIRECT_URI")
+	viper.BindEnv("spotify.refresh_token", "SPOTIFY_REFRESH_TOKEN")
+	viper.BindEnv("spotify.default_market", "SPOTIFY_DEFAULT_MARKET")
+	viper.BindEnv("spotify.recording.mode", "SPOTIFY_RECORDING_MODE")
+	viper.BindEnv("spotify.recording.cassette", "SPOTIFY_RECORDING_CASSETTE")
+	viper.BindEnv("spotify.api_base_url", "SPOTIFY_API_BASE_URL")
+	viper.BindEnv("spotify.accounts_base_url", "SPOTIFY_ACCOUNTS_BASE_URL")
+	viper.BindEnv("spotify.proxy_url", "SPOTIFY_PROXY_URL")
+	viper.BindEnv("spotify.ca_file", "SPOTIFY_CA_FILE")
 	viper.BindEnv("server.port", "SERVER_PORT")
+	viper.BindEnv("server.keepalive.interval", "SERVER_KEEPALIVE_INTERVAL")
+	viper.BindEnv("server.auth.keys_file", "SERVER_AUTH_KEYS_FILE")
+	viper.BindEnv("server.auth.resource_url", "SERVER_AUTH_RESOURCE_URL")
+	viper.BindEnv("tools.groups", "TOOLS_GROUPS")
+	viper.BindEnv("tools.disable", "TOOLS_DISABLE")
+	viper.BindEnv("tools.confirm_destructive", "TOOLS_CONFIRM_DESTRUCTIVE")
+	viper.BindEnv("tools.elicitation_fallback", "TOOLS_ELICITATION_FALLBACK")
+	viper.BindEnv("tools.journal.path", "TOOLS_JOURNAL_PATH")
 
 	// Set defaults
 	viper.SetDefault("server.port", 8080)
 	viper.SetDefault("server.read_timeout", 30)
 	viper.SetDefault("server.write_timeout", 30)
+	viper.SetDefault("server.prompts_file", "./configs/prompts.yaml")
+	viper.SetDefault("server.keepalive.interval", 30)
+	viper.SetDefault("server.keepalive.timeout", 10)
+	viper.SetDefault("server.keepalive.idle_timeout", 1800)
+	viper.SetDefault("server.auth.reload_interval", 10)
+	viper.SetDefault("spotify.api_base_url", "https://api.spotify.com/v1/")
+	viper.SetDefault("spotify.accounts_base_url", "https://accounts.spotify.com/")
+	viper.SetDefault("spotify.max_retries", 4)
+	viper.SetDefault("spotify.max_retry_wait", 20)
+	viper.SetDefault("spotify.breaker.failure_threshold", 5)
+	viper.SetDefault("spotify.breaker.cooldown", 30)
+	viper.SetDefault("spotify.cache.enabled", true)
+	viper.SetDefault("spotify.cache.backend", "memory")
+	viper.SetDefault("spotify.cache.dir", "./cache")
+	viper.SetDefault("spotify.cache.max_size_mb", 64)
+	viper.SetDefault("spotify.recording.mode", "off")
+	viper.SetDefault("spotify.recording.cassette", "./testdata/cassettes/session.json")
+	viper.SetDefault("tools.groups", []string{"catalog", "library", "playlists", "playback", "admin"})
+	viper.SetDefault("tools.confirm_destructive", true)
+	viper.SetDefault("tools.elicitation_fallback", "dry_run")
+	viper.SetDefault("tools.journal.path", "./data/journal.json")
+	viper.SetDefault("tools.journal.max_entries", 500)
 	viper.SetDefault("logging.level", "info")
 	viper.SetDefault("logging.format", "json")
 
@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+	"github.com/anuragkothare/spotify_mcp_server/internal/mcp"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// ResourceMetadataPath serves the OAuth protected resource metadata
+// (RFC 9728) that 401 responses point MCP clients to.
+const ResourceMetadataPath = "/.well-known/oauth-protected-resource"
+
+// hashPrefix marks a configured key hash; it is the only supported one.
+const hashPrefix = "sha256:"
+
+// apiKey is a configured key, looked up by the hash of the bearer token.
+type apiKey struct {
+	scope     *mcp.KeyScope
+	expiresAt time.Time
+}
+
+// Authenticator requires /mcp requests to carry a configured API key as a
+// bearer token, and limits each request to the tools its key allows. Keys
+// are only held as SHA-256 hashes. Keys from the keys file replace the
+// previous set whenever the file changes, so a key can be rotated by
+// adding its successor, moving clients over and removing it.
+type Authenticator struct {
+	mcpServer   *mcp.Server
+	logger      *logrus.Logger
+	static      []config.APIKeyConfig
+	keysFile    string
+	resourceURL string
+
+	mu   sync.RWMutex
+	keys map[string]*apiKey
+	// fileStamp identifies the version of the keys file last loaded
+	fileStamp string
+}
+
+// NewAuthenticator loads the configured keys. Authentication is only
+// enforced when some are configured or a keys file is set.
+func NewAuthenticator(mcpServer *mcp.Server, cfg config.AuthConfig, keys []config.APIKeyConfig, logger *logrus.Logger) (*Authenticator, error) {
+	a := &Authenticator{
+		mcpServer:   mcpServer,
+		logger:      logger,
+		static:      keys,
+		keysFile:    cfg.KeysFile,
+		resourceURL: cfg.ResourceURL,
+	}
+	if u, err := url.Parse(cfg.ResourceURL); cfg.ResourceURL != "" && (err != nil || u.Scheme == "" || u.Host == "") {
+		return nil, fmt.Errorf("invalid auth resource_url %q: expected an absolute URL", cfg.ResourceURL)
+	}
+	if err := a.Reload(); err != nil {
+		return nil, err
+	}
+	if !a.Enabled() {
+		logger.Warn("No API keys configured; /mcp accepts unauthenticated requests")
+	}
+	return a, nil
+}
+
+// Enabled reports whether requests must authenticate.
+func (a *Authenticator) Enabled() bool {
+	return len(a.static) > 0 || a.keysFile != ""
+}
+
+// Reload rereads the keys file. On error the previous keys stay in use.
+func (a *Authenticator) Reload() error {
+	configs := a.static
+	stamp := ""
+	if a.keysFile != "" {
+		info, err := os.Stat(a.keysFile)
+		if err != nil {
+			return fmt.Errorf("failed to read API keys file: %w", err)
+		}
+		stamp = fileStamp(info)
+
+		v := viper.New()
+		v.SetConfigFile(a.keysFile)
+		if err := v.ReadInConfig(); err != nil {
+			return fmt.Errorf("failed to read API keys file: %w", err)
+		}
+		var fileKeys []config.APIKeyConfig
+		if err := v.UnmarshalKey("api_keys", &fileKeys); err != nil {
+			return fmt.Errorf("failed to parse API keys file: %w", err)
+		}
+		configs = append(append([]config.APIKeyConfig{}, a.static...), fileKeys...)
+	}
+
+	keys := make(map[string]*apiKey, len(configs))
+	// Sessions are bound to key names, so the names must tell keys apart
+	names := make(map[string]bool, len(configs))
+	for _, cfg := range configs {
+		switch {
+		case cfg.Name == "":
+			return errors.New("api key: name is required")
+		case names[cfg.Name]:
+			return fmt.Errorf("api key %q: the name is used twice", cfg.Name)
+		}
+		names[cfg.Name] = true
+
+		hash, err := keyHash(cfg)
+		if err != nil {
+			return err
+		}
+		if _, ok := keys[hash]; ok {
+			return fmt.Errorf("api key %q: the same key is configured twice", cfg.Name)
+		}
+		key := &apiKey{}
+		if cfg.ExpiresAt != "" {
+			if key.expiresAt, err = time.Parse(time.RFC3339, cfg.ExpiresAt); err != nil {
+				return fmt.Errorf("api key %q: invalid expires_at: %w", cfg.Name, err)
+			}
+		}
+		if key.scope, err = a.mcpServer.NewKeyScope(cfg); err != nil {
+			return err
+		}
+		keys[hash] = key
+	}
+
+	a.mu.Lock()
+	a.keys = keys
+	a.fileStamp = stamp
+	a.mu.Unlock()
+	return nil
+}
+
+// keyHash returns the hex SHA-256 digest of a configured key.
+func keyHash(cfg config.APIKeyConfig) (string, error) {
+	switch {
+	case cfg.Hash != "" && cfg.Key != "":
+		return "", fmt.Errorf("api key %q: set either key or hash, not both", cfg.Name)
+	case cfg.Key != "":
+		return hashToken(cfg.Key), nil
+	case cfg.Hash == "":
+		return "", fmt.Errorf("api key %q: key or hash is required", cfg.Name)
+	}
+
+	digest, ok := strings.CutPrefix(strings.ToLower(cfg.Hash), hashPrefix)
+	if raw, err := hex.DecodeString(digest); !ok || err != nil || len(raw) != sha256.Size {
+		return "", fmt.Errorf("api key %q: hash must be %q followed by a hex SHA-256 digest", cfg.Name, hashPrefix)
+	}
+	return digest, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func fileStamp(info os.FileInfo) string {
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+}
+
+// RunReload reloads the keys file whenever it changes, checking every
+// interval. It returns when ctx is done.
+func (a *Authenticator) RunReload(ctx context.Context, interval time.Duration) {
+	if a.keysFile == "" || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.reloadIfChanged()
+		}
+	}
+}
+
+func (a *Authenticator) reloadIfChanged() {
+	info, err := os.Stat(a.keysFile)
+	if err != nil {
+		a.logger.Errorf("Failed to check API keys file, keeping the current keys: %v", err)
+		return
+	}
+	a.mu.RLock()
+	unchanged := fileStamp(info) == a.fileStamp
+	a.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	if err := a.Reload(); err != nil {
+		a.logger.Errorf("Failed to reload API keys, keeping the current keys: %v", err)
+		return
+	}
+	a.logger.Infof("Reloaded API keys from %s", a.keysFile)
+}
+
+var (
+	errMissingToken = errors.New("a bearer API key is required")
+	errInvalidToken = errors.New("the API key is not valid")
+	errExpiredToken = errors.New("the API key has expired")
+)
+
+// Middleware rejects requests without a valid API key and attaches the
+// key's scope to the rest.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scope, err := a.authenticate(r)
+		if err != nil {
+			a.unauthorized(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(mcp.WithKeyScope(r.Context(), scope)))
+	})
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (*mcp.KeyScope, error) {
+	// The scheme is case-insensitive
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, errMissingToken
+	}
+
+	a.mu.RLock()
+	key, ok := a.keys[hashToken(token)]
+	a.mu.RUnlock()
+	switch {
+	case !ok:
+		return nil, errInvalidToken
+	case !key.expiresAt.IsZero() && time.Now().After(key.expiresAt):
+		a.logger.Infof("Rejected expired API key %s", key.scope.Name())
+		return nil, errExpiredToken
+	}
+	return key.scope, nil
+}
+
+// unauthorized answers 401 with a challenge pointing to the resource
+// metadata, as MCP's authorization spec expects. Requests that presented
+// no key get no error code, per RFC 6750.
+func (a *Authenticator) unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	challenge := fmt.Sprintf(`Bearer resource_metadata=%q`, a.metadataURL(r))
+	if !errors.Is(err, errMissingToken) {
+		challenge += fmt.Sprintf(`, error="invalid_token", error_description=%q`, err.Error())
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(&mcp.MCPResponse{
+		JSONRPC: "2.0",
+		Error: &mcp.MCPError{
+			Code:    mcp.ErrorCodeInvalidRequest,
+			Message: "Unauthorized: " + err.Error(),
+		},
+	})
+}
+
+// HandleResourceMetadata describes /mcp as an OAuth protected resource.
+// There is no authorization server: keys are issued out of band, and the
+// scopes are the tool groups.
+func (a *Authenticator) HandleResourceMetadata(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"resource":                 a.resource(r),
+		"resource_name":            "Spotify MCP Server",
+		"bearer_methods_supported": []string{"header"},
+		"scopes_supported":         mcp.ToolGroups(),
+	})
+}
+
+// resource is the canonical URL of the /mcp endpoint.
+func (a *Authenticator) resource(r *http.Request) string {
+	if a.resourceURL != "" {
+		return a.resourceURL
+	}
+	return requestOrigin(r) + "/mcp"
+}
+
+func (a *Authenticator) metadataURL(r *http.Request) string {
+	if a.resourceURL != "" {
+		// Validated in NewAuthenticator
+		u, _ := url.Parse(a.resourceURL)
+		return u.Scheme + "://" + u.Host + ResourceMetadataPath
+	}
+	return requestOrigin(r) + ResourceMetadataPath
+}
+
+// requestOrigin is the scheme and host the client reached the server at,
+// honoring a TLS-terminating proxy's X-Forwarded-Proto.
+func requestOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
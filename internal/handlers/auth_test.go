@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+	"github.com/anuragkothare/spotify_mcp_server/internal/mcp"
+	"github.com/anuragkothare/spotify_mcp_server/internal/spotify/spotifytest"
+	"github.com/sirupsen/logrus"
+)
+
+const pingBody = `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+
+func TestUnauthorized(t *testing.T) {
+	server, _ := newTestServer(t, config.AuthConfig{},
+		config.APIKeyConfig{Name: "current", Hash: "sha256:" + hashToken("current-key"), Groups: mcp.ToolGroups()},
+		config.APIKeyConfig{Name: "expired", Key: "expired-key", ExpiresAt: time.Now().Add(-time.Hour).Format(time.RFC3339)},
+	)
+	metadata := fmt.Sprintf(`resource_metadata=%q`, server.URL+ResourceMetadataPath)
+
+	tests := []struct {
+		name          string
+		authorization string
+		error         string
+	}{
+		{"missing", "", ""},
+		{"not bearer", "Basic current-key", ""},
+		{"invalid", "Bearer wrong-key", "the API key is not valid"},
+		{"expired", "Bearer expired-key", "the API key has expired"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, server.URL+"/mcp", strings.NewReader(pingBody))
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			expectStatus(t, resp, http.StatusUnauthorized)
+
+			challenge := resp.Header.Get("WWW-Authenticate")
+			if !strings.HasPrefix(challenge, "Bearer ") || !strings.Contains(challenge, metadata) {
+				t.Errorf("got challenge %q, want one pointing to %s", challenge, metadata)
+			}
+			// Requests that presented no key get no error code
+			if hasError := strings.Contains(challenge, `error="invalid_token"`); hasError != (test.error != "") {
+				t.Errorf("got challenge %q", challenge)
+			}
+			if test.error != "" && !strings.Contains(challenge, test.error) {
+				t.Errorf("got challenge %q, want it to say %q", challenge, test.error)
+			}
+
+			var response mcp.MCPResponse
+			decodeBody(t, resp, &response)
+			if response.Error == nil || !strings.HasPrefix(response.Error.Message, "Unauthorized") {
+				t.Errorf("got %+v", response)
+			}
+		})
+	}
+
+	// The scheme is case-insensitive
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/mcp", strings.NewReader(pingBody))
+	req.Header.Set("Authorization", "bearer current-key")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	expectStatus(t, resp, http.StatusOK)
+}
+
+func TestKeyScope(t *testing.T) {
+	server, _ := newTestServer(t, config.AuthConfig{},
+		config.APIKeyConfig{Name: "catalog", Key: "catalog-key", Groups: []string{mcp.GroupCatalog}},
+	)
+
+	resp := send(t, server, http.MethodPost, "catalog-key", "",
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"list_my_playlists","arguments":{}}}`)
+	expectStatus(t, resp, http.StatusOK)
+	var response mcp.MCPResponse
+	decodeBody(t, resp, &response)
+	if response.Error == nil || !strings.Contains(response.Error.Message, "Tool not found") {
+		t.Errorf("got %+v, want the tool hidden", response)
+	}
+}
+
+func writeKeysFile(t *testing.T, path string, keys ...string) {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("api_keys:\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "  - name: %s\n    hash: sha256:%s\n    groups: [catalog]\n", key, hashToken(key))
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	writeKeysFile(t, path, "first-key")
+	server, auth := newTestServer(t, config.AuthConfig{KeysFile: path},
+		config.APIKeyConfig{Name: "static", Key: "static-key", Groups: mcp.ToolGroups()},
+	)
+	expectStatus(t, send(t, server, http.MethodPost, "first-key", "", pingBody), http.StatusOK)
+
+	// Rotate: the successor is added and the old key removed
+	writeKeysFile(t, path, "second-key")
+	// Make sure the file's stamp changes even on coarse clocks
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	auth.reloadIfChanged()
+	expectStatus(t, send(t, server, http.MethodPost, "first-key", "", pingBody), http.StatusUnauthorized)
+	expectStatus(t, send(t, server, http.MethodPost, "second-key", "", pingBody), http.StatusOK)
+	expectStatus(t, send(t, server, http.MethodPost, "static-key", "", pingBody), http.StatusOK)
+
+	// A broken file keeps the current keys
+	if err := os.WriteFile(path, []byte("api_keys:\n  - name: static\n    key: clash\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.Reload(); err == nil || !strings.Contains(err.Error(), "name is used twice") {
+		t.Errorf("got %v, want a duplicate name error", err)
+	}
+	expectStatus(t, send(t, server, http.MethodPost, "second-key", "", pingBody), http.StatusOK)
+	expectStatus(t, send(t, server, http.MethodPost, "clash", "", pingBody), http.StatusUnauthorized)
+}
+
+func TestNewAuthenticatorInvalid(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	mcpServer := mcp.NewServer(spotifytest.NewFake(), logger)
+
+	tests := []struct {
+		name string
+		cfg  config.AuthConfig
+		keys []config.APIKeyConfig
+	}{
+		{"no name", config.AuthConfig{}, []config.APIKeyConfig{{Key: "k"}}},
+		{"no key", config.AuthConfig{}, []config.APIKeyConfig{{Name: "a"}}},
+		{"key and hash", config.AuthConfig{}, []config.APIKeyConfig{{Name: "a", Key: "k", Hash: "sha256:" + hashToken("k")}}},
+		{"bad hash", config.AuthConfig{}, []config.APIKeyConfig{{Name: "a", Hash: "md5:abc"}}},
+		{"short hash", config.AuthConfig{}, []config.APIKeyConfig{{Name: "a", Hash: "sha256:abcd"}}},
+		{"same key", config.AuthConfig{}, []config.APIKeyConfig{{Name: "a", Key: "k"}, {Name: "b", Key: "k"}}},
+		{"bad expiry", config.AuthConfig{}, []config.APIKeyConfig{{Name: "a", Key: "k", ExpiresAt: "tomorrow"}}},
+		{"bad group", config.AuthConfig{}, []config.APIKeyConfig{{Name: "a", Key: "k", Groups: []string{"everything"}}}},
+		{"missing keys file", config.AuthConfig{KeysFile: filepath.Join(t.TempDir(), "missing.yaml")}, nil},
+		{"relative resource URL", config.AuthConfig{ResourceURL: "/mcp"}, nil},
+	}
+	for _, test := range tests {
+		if _, err := NewAuthenticator(mcpServer, test.cfg, test.keys, logger); err == nil {
+			t.Errorf("%s: got no error", test.name)
+		}
+	}
+}
+
+func TestResourceMetadata(t *testing.T) {
+	for _, test := range []struct {
+		resourceURL string
+		want        func(serverURL string) string
+	}{
+		{"", func(serverURL string) string { return serverURL + "/mcp" }},
+		{"https://mcp.example.com/mcp", func(string) string { return "https://mcp.example.com/mcp" }},
+	} {
+		server, _ := newTestServer(t, config.AuthConfig{ResourceURL: test.resourceURL},
+			config.APIKeyConfig{Name: "a", Key: "k"},
+		)
+
+		resp, err := http.Get(server.URL + ResourceMetadataPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		expectStatus(t, resp, http.StatusOK)
+		var metadata struct {
+			Resource string   `json:"resource"`
+			Scopes   []string `json:"scopes_supported"`
+		}
+		decodeBody(t, resp, &metadata)
+		if want := test.want(server.URL); metadata.Resource != want {
+			t.Errorf("got resource %q, want %q", metadata.Resource, want)
+		}
+		if !slices.Equal(metadata.Scopes, mcp.ToolGroups()) {
+			t.Errorf("got scopes %v", metadata.Scopes)
+		}
+
+		if test.resourceURL != "" {
+			challenge := send(t, server, http.MethodPost, "", "", pingBody).Header.Get("WWW-Authenticate")
+			if !strings.Contains(challenge, "https://mcp.example.com"+ResourceMetadataPath) {
+				t.Errorf("got challenge %q", challenge)
+			}
+		}
+	}
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/anuragkothare/spotify_mcp_server/internal/mcp"
+	"github.com/anuragkothare/spotify_mcp_server/internal/spotify"
 	"github.com/sirupsen/logrus"
 )
 
@@ -20,33 +24,188 @@ func NewHandler(mcpServer *mcp.Server, logger *logrus.Logger) *Handler {
 	}
 }
 
+// sessionHeader carries the session ID assigned on initialize.
+const sessionHeader = "Mcp-Session-Id"
+
 func (h *Handler) HandleMCP(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+	switch r.Method {
+	case http.MethodPost:
+		h.handlePost(w, r)
+	case http.MethodGet:
+		h.handleStream(w, r)
+	case http.MethodDelete:
+		h.handleDeleteSession(w, r)
+	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// maxRequestBody bounds the size of a POSTed message or batch.
+const maxRequestBody = 1 << 20
+
+func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	if err != nil {
+		h.logger.Errorf("Failed to read request: %v", err)
+		h.writeError(w, http.StatusRequestEntityTooLarge, mcp.ErrorCodeInvalidRequest, "Request body too large")
+		return
+	}
+	if !json.Valid(body) {
+		h.writeError(w, http.StatusBadRequest, mcp.ErrorCodeParseError, "Parse error: invalid JSON")
+		return
+	}
+
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		h.handleBatch(w, r, body)
 		return
 	}
 
 	var req mcp.MCPRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Errorf("Failed to decode request: %v", err)
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	if err := json.Unmarshal(body, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, mcp.ErrorCodeInvalidRequest, "Invalid request: expected a JSON-RPC object")
+		return
+	}
+
+	ctx := r.Context()
+
+	// Requests without a session header are still served, so clients that
+	// never initialize keep working; they just get no server messages
+	var created *mcp.Session
+	if id := r.Header.Get(sessionHeader); id != "" {
+		session, ok := h.mcpServer.Session(ctx, id)
+		if !ok {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		ctx = mcp.WithSession(ctx, session)
+	} else if req.Method == "initialize" {
+		created = h.mcpServer.NewSession(ctx)
+		ctx = mcp.WithSession(ctx, created)
+	}
+
+	h.extendWriteDeadline(w, h.mcpServer.RequestTimeout(&req))
+
+	if !req.IsNotification() && mcp.WantsProgress(&req) && acceptsEventStream(r) {
+		h.streamResponse(w, r.WithContext(ctx), &req)
+		return
+	}
+
+	response := h.mcpServer.HandleRequest(ctx, &req)
+	if response == nil {
+		// Notifications and cancelled requests have no response
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if created != nil {
+		if response.Error != nil {
+			h.mcpServer.CloseSession(created.ID)
+		} else {
+			w.Header().Set(sessionHeader, created.ID)
+		}
+	}
+
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+// handleBatch serves a JSON-RPC batch. Messages that aren't objects get an
+// invalid request error in their place.
+func (h *Handler) handleBatch(w http.ResponseWriter, r *http.Request, body []byte) {
+	var messages []json.RawMessage
+	if err := json.Unmarshal(body, &messages); err != nil {
+		h.writeError(w, http.StatusBadRequest, mcp.ErrorCodeParseError, "Parse error: invalid JSON")
 		return
 	}
 
-	response := h.mcpServer.HandleRequest(&req)
+	ctx := r.Context()
+	if id := r.Header.Get(sessionHeader); id != "" {
+		session, ok := h.mcpServer.Session(ctx, id)
+		if !ok {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		ctx = mcp.WithSession(ctx, session)
+	}
 
+	reqs := make([]*mcp.MCPRequest, len(messages))
+	var timeout time.Duration
+	for i, message := range messages {
+		reqs[i] = &mcp.MCPRequest{}
+		if err := json.Unmarshal(message, reqs[i]); err != nil {
+			// Left without a jsonrpc version, so it is answered as invalid
+			reqs[i] = &mcp.MCPRequest{}
+		}
+		timeout = max(timeout, h.mcpServer.RequestTimeout(reqs[i]))
+	}
+	h.extendWriteDeadline(w, timeout)
+
+	responses := h.mcpServer.HandleBatch(ctx, reqs)
+	if responses == nil {
+		// Only notifications
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if len(messages) == 0 {
+		// An empty batch is answered with a single error, not an array
+		h.writeJSON(w, http.StatusOK, responses[0])
+		return
+	}
+	h.writeJSON(w, http.StatusOK, responses)
+}
+
+// extendWriteDeadline gives tools with a long timeout time to respond.
+func (h *Handler) extendWriteDeadline(w http.ResponseWriter, timeout time.Duration) {
+	if timeout > 0 {
+		// Leave room to report the timeout error itself
+		http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + 5*time.Second))
+	}
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status, code int, message string) {
+	h.writeJSON(w, status, &mcp.MCPResponse{
+		JSONRPC: "2.0",
+		Error: &mcp.MCPError{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
 		h.logger.Errorf("Failed to encode response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(sessionHeader)
+	if id == "" {
+		http.Error(w, "Missing "+sessionHeader+" header", http.StatusBadRequest)
+		return
+	}
+	if _, ok := h.mcpServer.Session(r.Context(), id); !ok || !h.mcpServer.CloseSession(id) {
+		http.Error(w, "Session not found", http.StatusNotFound)
 		return
 	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *Handler) HandleHealth(w http.ResponseWriter, r *http.Request) {
+	spotifyStatus := h.mcpServer.SpotifyStatus()
+
+	// The server keeps answering from cache while Spotify is down, so an
+	// open breaker is reported as degraded rather than unhealthy.
+	status := "healthy"
+	if spotifyStatus.State != spotify.BreakerClosed {
+		status = "degraded"
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"status": "healthy",
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  status,
+		"spotify": spotifyStatus,
 	})
 }
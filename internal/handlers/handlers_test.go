@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+	"github.com/anuragkothare/spotify_mcp_server/internal/mcp"
+	"github.com/anuragkothare/spotify_mcp_server/internal/spotify/spotifytest"
+	"github.com/sirupsen/logrus"
+)
+
+// newTestServer serves /mcp over the fake the way main wires it, with the
+// given API keys.
+func newTestServer(t *testing.T, cfg config.AuthConfig, keys ...config.APIKeyConfig) (*httptest.Server, *Authenticator) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	mcpServer := mcp.NewServer(spotifytest.NewFake(), logger)
+	if err := mcpServer.ConfigureTools(config.ToolsConfig{Groups: mcp.ToolGroups()}); err != nil {
+		t.Fatalf("ConfigureTools: %v", err)
+	}
+	auth, err := NewAuthenticator(mcpServer, cfg, keys, logger)
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+
+	handler := NewHandler(mcpServer, logger)
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", auth.Middleware(http.HandlerFunc(handler.HandleMCP)))
+	mux.HandleFunc(ResourceMetadataPath, auth.HandleResourceMetadata)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, auth
+}
+
+// send makes a request to /mcp with an API key and session, when given.
+func send(t *testing.T, server *httptest.Server, method, key, session, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+"/mcp", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	if session != "" {
+		req.Header.Set(sessionHeader, session)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func expectStatus(t *testing.T, resp *http.Response, status int) {
+	t.Helper()
+	if resp.StatusCode != status {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("got status %d (%s), want %d", resp.StatusCode, body, status)
+	}
+}
+
+func decodeBody(t *testing.T, resp *http.Response, v interface{}) {
+	t.Helper()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+}
+
+func TestPostRequest(t *testing.T) {
+	server, _ := newTestServer(t, config.AuthConfig{})
+
+	resp := send(t, server, http.MethodPost, "", "", `{"jsonrpc":"2.0","id":"a","method":"tools/list"}`)
+	expectStatus(t, resp, http.StatusOK)
+	var response mcp.MCPResponse
+	decodeBody(t, resp, &response)
+	if response.ID != "a" || response.Error != nil {
+		t.Errorf("got %+v", response)
+	}
+
+	expectStatus(t, send(t, server, http.MethodPost, "", "", `{"jsonrpc":"2.0","method":"notifications/initialized"}`), http.StatusAccepted)
+
+	resp = send(t, server, http.MethodPost, "", "", `{"jsonrpc":`)
+	expectStatus(t, resp, http.StatusBadRequest)
+	decodeBody(t, resp, &response)
+	if response.Error == nil || response.Error.Code != mcp.ErrorCodeParseError {
+		t.Errorf("got %+v, want a parse error", response)
+	}
+
+	expectStatus(t, send(t, server, http.MethodPut, "", "", ""), http.StatusMethodNotAllowed)
+}
+
+func TestPostBatch(t *testing.T) {
+	server, _ := newTestServer(t, config.AuthConfig{})
+
+	resp := send(t, server, http.MethodPost, "", "", `[
+		{"jsonrpc":"2.0","id":1,"method":"tools/list"},
+		{"jsonrpc":"2.0","method":"notifications/initialized"},
+		42,
+		{"jsonrpc":"2.0","id":2,"method":"resources/list"}
+	]`)
+	expectStatus(t, resp, http.StatusOK)
+	var responses []mcp.MCPResponse
+	decodeBody(t, resp, &responses)
+	if len(responses) != 3 {
+		t.Fatalf("got %d responses, want 3", len(responses))
+	}
+	if responses[0].ID != 1.0 || responses[0].Error != nil {
+		t.Errorf("got %+v for the first tools/list", responses[0])
+	}
+	if responses[1].Error == nil || responses[1].Error.Code != mcp.ErrorCodeInvalidRequest {
+		t.Errorf("got %+v for the non-object", responses[1])
+	}
+	if responses[2].ID != 2.0 || responses[2].Error != nil {
+		t.Errorf("got %+v for resources/list", responses[2])
+	}
+
+	expectStatus(t, send(t, server, http.MethodPost, "", "", `[{"jsonrpc":"2.0","method":"notifications/initialized"}]`), http.StatusAccepted)
+
+	// An empty batch gets a single error, not an array
+	resp = send(t, server, http.MethodPost, "", "", `[]`)
+	expectStatus(t, resp, http.StatusOK)
+	var response mcp.MCPResponse
+	decodeBody(t, resp, &response)
+	if response.Error == nil || response.Error.Code != mcp.ErrorCodeInvalidRequest {
+		t.Errorf("got %+v, want an invalid request error", response)
+	}
+}
+
+const initializeBody = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`
+
+// initialize opens a session and returns its ID.
+func initialize(t *testing.T, server *httptest.Server, key string) string {
+	t.Helper()
+	resp := send(t, server, http.MethodPost, key, "", initializeBody)
+	expectStatus(t, resp, http.StatusOK)
+	session := resp.Header.Get(sessionHeader)
+	if session == "" {
+		t.Fatal("initialize returned no session ID")
+	}
+	return session
+}
+
+func TestSessions(t *testing.T) {
+	server, _ := newTestServer(t, config.AuthConfig{})
+	session := initialize(t, server, "")
+
+	expectStatus(t, send(t, server, http.MethodPost, "", session, `{"jsonrpc":"2.0","id":1,"method":"ping"}`), http.StatusOK)
+	expectStatus(t, send(t, server, http.MethodPost, "", "unknown", `{"jsonrpc":"2.0","id":1,"method":"ping"}`), http.StatusNotFound)
+	expectStatus(t, send(t, server, http.MethodDelete, "", "", ""), http.StatusBadRequest)
+	expectStatus(t, send(t, server, http.MethodDelete, "", session, ""), http.StatusNoContent)
+	expectStatus(t, send(t, server, http.MethodPost, "", session, `{"jsonrpc":"2.0","id":1,"method":"ping"}`), http.StatusNotFound)
+}
+
+func TestSessionsBoundToKey(t *testing.T) {
+	server, _ := newTestServer(t, config.AuthConfig{},
+		config.APIKeyConfig{Name: "alice", Key: "alice-key", Groups: mcp.ToolGroups()},
+		config.APIKeyConfig{Name: "bob", Key: "bob-key", Groups: mcp.ToolGroups()},
+	)
+	session := initialize(t, server, "alice-key")
+
+	// Another key can't use, batch into, stream from or close the session
+	expectStatus(t, send(t, server, http.MethodPost, "bob-key", session, `{"jsonrpc":"2.0","id":1,"method":"ping"}`), http.StatusNotFound)
+	expectStatus(t, send(t, server, http.MethodPost, "bob-key", session, `[{"jsonrpc":"2.0","id":1,"method":"ping"}]`), http.StatusNotFound)
+	expectStatus(t, send(t, server, http.MethodGet, "bob-key", session, ""), http.StatusNotFound)
+	expectStatus(t, send(t, server, http.MethodDelete, "bob-key", session, ""), http.StatusNotFound)
+
+	expectStatus(t, send(t, server, http.MethodPost, "alice-key", session, `{"jsonrpc":"2.0","id":1,"method":"ping"}`), http.StatusOK)
+	expectStatus(t, send(t, server, http.MethodDelete, "alice-key", session, ""), http.StatusNoContent)
+}
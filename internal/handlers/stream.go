@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/mcp"
+)
+
+// streamKeepAlive is how often an idle GET stream sends a comment so
+// proxies don't close it.
+const streamKeepAlive = 25 * time.Second
+
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// eventWriter writes server-sent events and flushes each one.
+type eventWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func newEventWriter(w http.ResponseWriter) *eventWriter {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// Flush the headers so the client sees the stream open before the
+	// first event
+	rc := http.NewResponseController(w)
+	rc.Flush()
+	return &eventWriter{w: w, rc: rc}
+}
+
+func (e *eventWriter) write(data []byte) error {
+	return e.raw(fmt.Sprintf("event: message\ndata: %s\n\n", data))
+}
+
+func (e *eventWriter) raw(event string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return http.ErrHandlerTimeout
+	}
+	if _, err := fmt.Fprint(e.w, event); err != nil {
+		return err
+	}
+	return e.rc.Flush()
+}
+
+// close stops further writes, e.g. from a tool goroutine that outlives the
+// request.
+func (e *eventWriter) close() {
+	e.mu.Lock()
+	e.closed = true
+	e.mu.Unlock()
+}
+
+// streamResponse answers a POST as an event stream so notifications about
+// the request, such as progress, reach the client before the response.
+func (h *Handler) streamResponse(w http.ResponseWriter, r *http.Request, req *mcp.MCPRequest) {
+	events := newEventWriter(w)
+	defer events.close()
+
+	ctx := mcp.WithMessageSink(r.Context(), func(message interface{}) {
+		data, err := json.Marshal(message)
+		if err != nil {
+			return
+		}
+		if err := events.write(data); err != nil {
+			h.logger.Debugf("Failed to stream message: %v", err)
+		}
+	})
+
+	response := h.mcpServer.HandleRequest(ctx, req)
+	if response == nil {
+		return
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		h.logger.Errorf("Failed to encode response: %v", err)
+		return
+	}
+	if err := events.write(data); err != nil {
+		h.logger.Errorf("Failed to stream response: %v", err)
+	}
+}
+
+// handleStream opens the session's stream of server-initiated messages.
+func (h *Handler) handleStream(w http.ResponseWriter, r *http.Request) {
+	if !acceptsEventStream(r) {
+		http.Error(w, "Not acceptable: GET requires Accept: text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	session, ok := h.mcpServer.Session(r.Context(), r.Header.Get(sessionHeader))
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	messages, release, ok := session.Stream()
+	if !ok {
+		http.Error(w, "Stream already open for this session", http.StatusConflict)
+		return
+	}
+	defer release()
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Debugf("Failed to clear write deadline: %v", err)
+	}
+
+	events := newEventWriter(w)
+	defer events.close()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-session.Done():
+			return
+		case data := <-messages:
+			if err := events.write(data); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if err := events.raw(": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+)
+
+// Tool groups let deployments switch related tools on or off together.
+const (
+	GroupCatalog   = "catalog"
+	GroupLibrary   = "library"
+	GroupPlaylists = "playlists"
+	GroupPlayback  = "playback"
+	GroupAdmin     = "admin"
+)
+
+var toolGroups = []string{GroupCatalog, GroupLibrary, GroupPlaylists, GroupPlayback, GroupAdmin}
+
+// toolPolicy is the server-wide selection of enabled tools.
+type toolPolicy struct {
+	groups  map[string]bool
+	enable  map[string]bool
+	disable map[string]bool
+}
+
+func (p *toolPolicy) allows(tool Tool) bool {
+	if p.disable[tool.Name] {
+		return false
+	}
+	// Ungrouped tools can only be switched off by name
+	return tool.Group == "" || p.groups[tool.Group] || p.enable[tool.Name]
+}
+
+// resourceGroups and sourceGroups put resources, by the host of their
+// spotify:// URI, and completion sources in the group of the tools that
+// return the same data, so that API key scopes cover them too.
+var (
+	resourceGroups = map[string]string{
+		"track":    GroupCatalog,
+		"artist":   GroupCatalog,
+		"album":    GroupCatalog,
+		"user":     GroupCatalog,
+		"browse":   GroupCatalog,
+		"me":       GroupLibrary,
+		"playlist": GroupPlaylists,
+	}
+	sourceGroups = map[string]string{
+		completeArtist:   GroupCatalog,
+		completeTrack:    GroupCatalog,
+		completeCategory: GroupCatalog,
+		completePlaylist: GroupPlaylists,
+		completeDevice:   GroupPlayback,
+	}
+)
+
+// ToolGroups returns the names of the tool groups, which are also the
+// scopes API keys are granted.
+func ToolGroups() []string {
+	return slices.Clone(toolGroups)
+}
+
+// KeyScope is the allowlist of a client API key: the tool groups it is
+// scoped to, and tools granted individually.
+type KeyScope struct {
+	name   string
+	groups map[string]bool
+	tools  map[string]bool
+}
+
+func (p *KeyScope) allows(tool Tool) bool {
+	return tool.Group != "" && p.groups[tool.Group] || p.tools[tool.Name]
+}
+
+// Name is the name of the key the scope belongs to, or "" for none.
+func (p *KeyScope) Name() string {
+	if p == nil {
+		return ""
+	}
+	return p.name
+}
+
+// NewKeyScope builds the allowlist of a configured API key. Tool names
+// that aren't registered are only warned about, since tools can be
+// registered later.
+func (s *Server) NewKeyScope(key config.APIKeyConfig) (*KeyScope, error) {
+	if err := validateGroups(key.Groups); err != nil {
+		return nil, fmt.Errorf("api key %q: %w", key.Name, err)
+	}
+	s.warnUnknownTools(key.Tools)
+	return &KeyScope{
+		name:   key.Name,
+		groups: stringSet(key.Groups),
+		tools:  stringSet(key.Tools),
+	}, nil
+}
+
+type keyScopeContextKey struct{}
+
+// WithKeyScope limits a request to the tools allowed by the API key it
+// was authenticated with.
+func WithKeyScope(ctx context.Context, scope *KeyScope) context.Context {
+	return context.WithValue(ctx, keyScopeContextKey{}, scope)
+}
+
+func keyScopeFromContext(ctx context.Context) *KeyScope {
+	scope, _ := ctx.Value(keyScopeContextKey{}).(*KeyScope)
+	return scope
+}
+
+func stringSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+func validateGroups(groups []string) error {
+	for _, group := range groups {
+		if !slices.Contains(toolGroups, group) {
+			return fmt.Errorf("unknown tool group %q, expected one of %v", group, toolGroups)
+		}
+	}
+	return nil
+}
+
+// ConfigureTools applies the tool selection and how destructive tools are
+// confirmed. Tool names that aren't registered are only warned about,
+// since tools can be registered later. API keys are checked by the HTTP
+// handlers; see NewKeyScope.
+func (s *Server) ConfigureTools(cfg config.ToolsConfig) error {
+	if err := validateGroups(cfg.Groups); err != nil {
+		return err
+	}
+	fallback := cfg.ElicitationFallback
+	if fallback == "" {
+		fallback = fallbackDryRun
+	}
+	if err := validateFallback(fallback); err != nil {
+		return err
+	}
+	policy := &toolPolicy{
+		groups:  stringSet(cfg.Groups),
+		enable:  stringSet(cfg.Enable),
+		disable: stringSet(cfg.Disable),
+	}
+	s.warnUnknownTools(cfg.Enable)
+	s.warnUnknownTools(cfg.Disable)
+
+	s.accessMu.Lock()
+	s.toolPolicy = policy
+	s.confirm = confirmPolicy{enabled: cfg.ConfirmDestructive, fallback: fallback}
+	s.accessMu.Unlock()
+
+	s.broadcast("notifications/tools/list_changed", nil)
+	return nil
+}
+
+func (s *Server) warnUnknownTools(names []string) {
+	for _, name := range names {
+		if _, ok := s.lookupTool(name); !ok {
+			s.logger.Warnf("Tool config names unknown tool %s", name)
+		}
+	}
+}
+
+// groupAllowed reports whether the request's API key, if any, is scoped to
+// group. Data outside every group is left to its own checks.
+func groupAllowed(ctx context.Context, group string) bool {
+	scope := keyScopeFromContext(ctx)
+	return scope == nil || group == "" || scope.groups[group]
+}
+
+// resourceAllowed reports whether the request's client may read a resource
+// URI or list a URI template.
+func resourceAllowed(ctx context.Context, uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return true
+	}
+	return groupAllowed(ctx, resourceGroups[u.Host])
+}
+
+// promptAllowed reports whether the request's client may get a prompt: it
+// needs every resource the prompt embeds and every lookup its arguments
+// use.
+func promptAllowed(ctx context.Context, prompt *Prompt) bool {
+	for _, uri := range prompt.Resources {
+		if !resourceAllowed(ctx, uri) {
+			return false
+		}
+	}
+	for _, arg := range prompt.Arguments {
+		if !groupAllowed(ctx, sourceGroups[arg.Resolve]) || !groupAllowed(ctx, sourceGroups[arg.Complete]) {
+			return false
+		}
+	}
+	return true
+}
+
+// toolAllowed reports whether the request's client may see and call tool.
+func (s *Server) toolAllowed(ctx context.Context, tool Tool) bool {
+	s.accessMu.RLock()
+	defer s.accessMu.RUnlock()
+
+	if s.toolPolicy != nil && !s.toolPolicy.allows(tool) {
+		return false
+	}
+	if scope := keyScopeFromContext(ctx); scope != nil && !scope.allows(tool) {
+		return false
+	}
+	return true
+}
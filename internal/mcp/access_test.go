@@ -0,0 +1,183 @@
+package mcp
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+)
+
+// scopedContext authenticates requests with an API key.
+func scopedContext(t *testing.T, s *Server, key config.APIKeyConfig) context.Context {
+	t.Helper()
+	scope, err := s.NewKeyScope(key)
+	if err != nil {
+		t.Fatalf("NewKeyScope: %v", err)
+	}
+	return WithKeyScope(context.Background(), scope)
+}
+
+func TestKeyScopeTools(t *testing.T) {
+	s, fake := newTestServer(t)
+	ctx := scopedContext(t, s, config.APIKeyConfig{
+		Name:   "reader",
+		Groups: []string{GroupCatalog},
+		Tools:  []string{"get_playlist_items"},
+	})
+
+	var list struct {
+		Tools []struct {
+			Name string `json:"name"`
+		} `json:"tools"`
+	}
+	decodeResult(t, s.HandleRequest(ctx, newRequest(t, 1, "tools/list", nil)), &list)
+	var names []string
+	for _, tool := range list.Tools {
+		names = append(names, tool.Name)
+	}
+	for _, name := range []string{"search_tracks", "get_track", "get_playlist_items"} {
+		if !slices.Contains(names, name) {
+			t.Errorf("tools/list is missing %s", name)
+		}
+	}
+	for _, name := range []string{"add_playlist_items", "undo_change", "list_my_playlists", "get_cache_stats"} {
+		if slices.Contains(names, name) {
+			t.Errorf("tools/list includes %s", name)
+		}
+	}
+
+	toolOutput[struct{}](t, callTool(t, s, ctx, "get_playlist_items", map[string]interface{}{"playlist_id": roadTripID}))
+	expectError(t, callTool(t, s, ctx, "add_playlist_items", map[string]interface{}{
+		"playlist_id": roadTripID,
+		"uris":        []string{trackC},
+	}), ErrorCodeInvalidParams)
+	expectItems(t, fake, roadTripID, trackA, trackB)
+
+	// A key with no scopes gets nothing
+	none := scopedContext(t, s, config.APIKeyConfig{Name: "none"})
+	expectError(t, callTool(t, s, none, "search_tracks", map[string]interface{}{"query": "queen"}), ErrorCodeInvalidParams)
+}
+
+func TestKeyScopeResources(t *testing.T) {
+	s, _ := newTestServer(t)
+	ctx := scopedContext(t, s, config.APIKeyConfig{Name: "catalog", Groups: []string{GroupCatalog}})
+
+	var templates ListResourceTemplatesResponse
+	decodeResult(t, s.HandleRequest(ctx, newRequest(t, 1, "resources/templates/list", nil)), &templates)
+	for _, template := range templates.ResourceTemplates {
+		if template.URITemplate == "spotify://playlist/{id}" {
+			t.Errorf("templates include %s", template.URITemplate)
+		}
+	}
+
+	var resources ListResourcesResponse
+	decodeResult(t, s.HandleRequest(ctx, newRequest(t, 1, "resources/list", nil)), &resources)
+	for _, resource := range resources.Resources {
+		if resource.URI == currentUserURI {
+			t.Errorf("resources include %s", resource.URI)
+		}
+	}
+	if len(resources.Resources) == 0 || resources.Resources[0].URI != categoriesURI {
+		t.Errorf("got resources %+v, want the browse categories", resources.Resources)
+	}
+
+	read := func(uri string) *MCPResponse {
+		return s.HandleRequest(ctx, newRequest(t, 1, "resources/read", map[string]interface{}{"uri": uri}))
+	}
+	decodeResult(t, read("spotify://track/4uLU6hMCjMI75M1A2tKUQC"), &ReadResourceResponse{})
+	expectError(t, read(currentUserURI), ErrorCodeResourceNotFound)
+	expectError(t, read("spotify://playlist/"+roadTripID), ErrorCodeResourceNotFound)
+
+	// Without the catalog group there are no categories to list either
+	library := scopedContext(t, s, config.APIKeyConfig{Name: "library", Groups: []string{GroupLibrary}})
+	resources = ListResourcesResponse{}
+	decodeResult(t, s.HandleRequest(library, newRequest(t, 1, "resources/list", nil)), &resources)
+	var uris []string
+	for _, resource := range resources.Resources {
+		uris = append(uris, resource.URI)
+	}
+	if !slices.Equal(uris, []string{currentUserURI, topItemsURI}) {
+		t.Errorf("got resources %v, want only %s and %s", uris, currentUserURI, topItemsURI)
+	}
+}
+
+func TestKeyScopePrompts(t *testing.T) {
+	s, _ := newTestServer(t)
+	ctx := scopedContext(t, s, config.APIKeyConfig{Name: "catalog", Groups: []string{GroupCatalog}})
+
+	var list ListPromptsResponse
+	decodeResult(t, s.HandleRequest(ctx, newRequest(t, 1, "prompts/list", nil)), &list)
+	var names []string
+	for _, prompt := range list.Prompts {
+		names = append(names, prompt.Name)
+	}
+	if !slices.Contains(names, "build_playlist") || slices.Contains(names, "summarize_listening") {
+		t.Errorf("got prompts %v", names)
+	}
+
+	// summarize_listening embeds the user's profile, which needs library
+	expectError(t, s.HandleRequest(ctx, newRequest(t, 1, "prompts/get", map[string]interface{}{
+		"name": "summarize_listening",
+	})), ErrorCodeInvalidParams)
+}
+
+func TestKeyScopeCompletion(t *testing.T) {
+	s, _ := newTestServer(t)
+	ctx := scopedContext(t, s, config.APIKeyConfig{Name: "catalog", Groups: []string{GroupCatalog}})
+	complete := func(ref map[string]interface{}, argument, value string) *MCPResponse {
+		return s.HandleRequest(ctx, newRequest(t, 1, "completion/complete", map[string]interface{}{
+			"ref":      ref,
+			"argument": map[string]interface{}{"name": argument, "value": value},
+		}))
+	}
+
+	var result CompleteResponse
+	decodeResult(t, complete(map[string]interface{}{"type": "ref/prompt", "name": "build_playlist"}, "mood", "par"), &result)
+	if !slices.Contains(result.Completion.Values, "Party") {
+		t.Errorf("got completions %v, want Party", result.Completion.Values)
+	}
+
+	expectError(t, complete(map[string]interface{}{"type": "ref/resource", "uri": "spotify://playlist/{id}"}, "id", ""), ErrorCodeInvalidParams)
+	expectError(t, complete(map[string]interface{}{"type": "ref/prompt", "name": "summarize_listening"}, "x", ""), ErrorCodeInvalidParams)
+
+	// Playlist IDs complete spotify://playlist/{id}, so they follow its group
+	playlistRef := map[string]interface{}{"type": "ref/resource", "uri": "spotify://playlist/{id}"}
+	ctx = scopedContext(t, s, config.APIKeyConfig{Name: "library", Groups: []string{GroupLibrary}})
+	expectError(t, complete(playlistRef, "id", ""), ErrorCodeInvalidParams)
+	ctx = scopedContext(t, s, config.APIKeyConfig{Name: "playlists", Groups: []string{GroupPlaylists}})
+	result = CompleteResponse{}
+	decodeResult(t, complete(playlistRef, "id", ""), &result)
+	if !slices.Contains(result.Completion.Values, roadTripID) {
+		t.Errorf("got completions %v, want %s", result.Completion.Values, roadTripID)
+	}
+}
+
+func TestToolNotFound(t *testing.T) {
+	s, _ := newTestServer(t)
+	if err := s.ConfigureTools(config.ToolsConfig{Groups: []string{GroupCatalog}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Disabled tools look like unknown ones
+	for _, name := range []string{"no_such_tool", "list_my_playlists"} {
+		expectError(t, callTool(t, s, context.Background(), name, map[string]interface{}{}), ErrorCodeInvalidParams)
+	}
+}
+
+func TestSessionBoundToKey(t *testing.T) {
+	s, _ := newTestServer(t)
+	alice := scopedContext(t, s, config.APIKeyConfig{Name: "alice", Groups: ToolGroups()})
+	bob := scopedContext(t, s, config.APIKeyConfig{Name: "bob", Groups: ToolGroups()})
+
+	session := s.NewSession(alice)
+	if _, ok := s.Session(alice, session.ID); !ok {
+		t.Error("the creating key can't find its session")
+	}
+	if _, ok := s.Session(bob, session.ID); ok {
+		t.Error("another key found the session")
+	}
+	if _, ok := s.Session(context.Background(), session.ID); ok {
+		t.Error("an unauthenticated request found the session")
+	}
+}
@@ -0,0 +1,68 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// maxBatchSize bounds the requests accepted in one JSON-RPC batch.
+	maxBatchSize = 50
+
+	// batchConcurrency bounds how many requests of a batch run at once, so
+	// a batch can't burst past Spotify's rate limits on its own.
+	batchConcurrency = 8
+)
+
+// HandleBatch dispatches the requests of a JSON-RPC batch concurrently and
+// returns their responses in request order, leaving out notifications. It
+// returns nil when no response is due.
+func (s *Server) HandleBatch(ctx context.Context, reqs []*MCPRequest) []*MCPResponse {
+	if len(reqs) == 0 || len(reqs) > maxBatchSize {
+		return []*MCPResponse{{
+			JSONRPC: "2.0",
+			Error: &MCPError{
+				Code:    ErrorCodeInvalidRequest,
+				Message: "Invalid request: a batch must contain between 1 and 50 messages",
+			},
+		}}
+	}
+
+	responses := make([]*MCPResponse, len(reqs))
+	slots := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		// initialize creates the session, so it must come on its own
+		if req.Method == "initialize" {
+			responses[i] = &MCPResponse{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error: &MCPError{
+					Code:    ErrorCodeInvalidRequest,
+					Message: "Invalid request: initialize must not be part of a batch",
+				},
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			responses[i] = s.HandleRequest(ctx, req)
+		}()
+	}
+	wg.Wait()
+
+	result := responses[:0]
+	for _, response := range responses {
+		if response != nil {
+			result = append(result, response)
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
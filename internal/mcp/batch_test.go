@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHandleBatch(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	responses := s.HandleBatch(context.Background(), []*MCPRequest{
+		newRequest(t, 1, "tools/list", nil),
+		newRequest(t, nil, "notifications/initialized", nil),
+		newRequest(t, "two", "tools/list", nil),
+		newRequest(t, 3, "no/such/method", nil),
+	})
+	if len(responses) != 3 {
+		t.Fatalf("got %d responses, want 3", len(responses))
+	}
+	// Numeric IDs come back as JSON decodes them
+	for i, id := range []interface{}{1.0, "two", 3.0} {
+		if responses[i].ID != id {
+			t.Errorf("response %d has ID %v, want %v", i, responses[i].ID, id)
+		}
+	}
+	if responses[0].Error != nil || responses[1].Error != nil {
+		t.Errorf("got errors %+v, %+v", responses[0].Error, responses[1].Error)
+	}
+	expectError(t, responses[2], ErrorCodeMethodNotFound)
+}
+
+func TestHandleBatchNotifications(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	responses := s.HandleBatch(context.Background(), []*MCPRequest{
+		newRequest(t, nil, "notifications/initialized", nil),
+		newRequest(t, nil, "tools/list", nil),
+	})
+	if responses != nil {
+		t.Errorf("got %+v, want no responses", responses)
+	}
+}
+
+func TestHandleBatchInvalid(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	responses := s.HandleBatch(context.Background(), nil)
+	if len(responses) != 1 {
+		t.Fatalf("empty batch got %d responses, want 1", len(responses))
+	}
+	expectError(t, responses[0], ErrorCodeInvalidRequest)
+
+	tooMany := make([]*MCPRequest, maxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = newRequest(t, i, "tools/list", nil)
+	}
+	if responses := s.HandleBatch(context.Background(), tooMany); len(responses) != 1 || responses[0].Error == nil {
+		t.Errorf("oversized batch got %+v, want a single error", responses)
+	}
+
+	responses = s.HandleBatch(context.Background(), []*MCPRequest{
+		newRequest(t, 1, "initialize", map[string]interface{}{"protocolVersion": "2025-06-18"}),
+		{},
+	})
+	if len(responses) != 2 {
+		t.Fatalf("got %d responses, want 2", len(responses))
+	}
+	expectError(t, responses[0], ErrorCodeInvalidRequest)
+	expectError(t, responses[1], ErrorCodeInvalidRequest)
+}
@@ -0,0 +1,88 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// CancelledNotification represents the params of notifications/cancelled
+type CancelledNotification struct {
+	RequestID interface{} `json:"requestId"`
+	Reason    string      `json:"reason,omitempty"`
+}
+
+// requestKey turns a JSON-RPC ID into a map key. The type is kept, since
+// JSON-RPC treats the number 1 and the string "1" as different IDs.
+func requestKey(id interface{}) string {
+	return fmt.Sprintf("%T:%v", id, id)
+}
+
+// inFlightRequest is a running request that can be cancelled. Entries are
+// compared by pointer, so a finished request only removes its own entry.
+type inFlightRequest struct {
+	cancel context.CancelFunc
+}
+
+// inFlightKey scopes a request ID to its session, or without one to the
+// API key, since clients pick IDs independently. Requests with neither
+// can't be told apart from other clients' and aren't tracked.
+func inFlightKey(ctx context.Context, id interface{}) (string, bool) {
+	if session := SessionFromContext(ctx); session != nil {
+		return session.ID + "/" + requestKey(id), true
+	}
+	if name := keyScopeFromContext(ctx).Name(); name != "" {
+		return "key:" + name + "/" + requestKey(id), true
+	}
+	return "", false
+}
+
+// trackRequest registers cancel under the request ID until the returned
+// release function is called.
+func (s *Server) trackRequest(ctx context.Context, id interface{}, cancel context.CancelFunc) func() {
+	key, ok := inFlightKey(ctx, id)
+	if id == nil || !ok {
+		return func() {}
+	}
+
+	entry := &inFlightRequest{cancel: cancel}
+	s.inFlightMu.Lock()
+	s.inFlight[key] = entry
+	s.inFlightMu.Unlock()
+
+	return func() {
+		s.inFlightMu.Lock()
+		// A later request may have reused the ID
+		if s.inFlight[key] == entry {
+			delete(s.inFlight, key)
+		}
+		s.inFlightMu.Unlock()
+	}
+}
+
+func (s *Server) handleCancelled(ctx context.Context, req *MCPRequest) {
+	var params CancelledNotification
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.RequestID == nil {
+		s.logger.WithContext(ctx).Warnf("Ignoring malformed cancellation: %s", string(req.Params))
+		return
+	}
+
+	key, ok := inFlightKey(ctx, params.RequestID)
+	if !ok {
+		s.logger.WithContext(ctx).Debugf("Ignoring cancellation of %v sent without a session or API key", params.RequestID)
+		return
+	}
+
+	s.inFlightMu.Lock()
+	entry, ok := s.inFlight[key]
+	s.inFlightMu.Unlock()
+
+	if !ok {
+		// The request already finished or was never seen
+		s.logger.WithContext(ctx).Debugf("No in-flight request %v to cancel", params.RequestID)
+		return
+	}
+
+	s.logger.WithContext(ctx).Infof("Cancelling request %v: %s", params.RequestID, params.Reason)
+	entry.cancel()
+}
@@ -0,0 +1,131 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+)
+
+func TestHandleRequestCancelled(t *testing.T) {
+	s, _ := newTestServer(t)
+	started := make(chan struct{})
+	s.RegisterTool(NewTool(Tool{Name: "wait", Description: "Wait until cancelled"}, func(ctx context.Context, _ noArgs) (struct{}, error) {
+		close(started)
+		<-ctx.Done()
+		return struct{}{}, ctx.Err()
+	}))
+
+	ctx := WithSession(context.Background(), s.NewSession(context.Background()))
+	responses := make(chan *MCPResponse, 1)
+	go func() {
+		responses <- s.HandleRequest(ctx, newRequest(t, "call-1", "tools/call", map[string]interface{}{"name": "wait"}))
+	}()
+	<-started
+
+	cancel := newRequest(t, nil, "notifications/cancelled", map[string]interface{}{"requestId": "call-1"})
+	if response := s.HandleRequest(ctx, cancel); response != nil {
+		t.Errorf("cancellation got response %+v", response)
+	}
+	select {
+	case response := <-responses:
+		if response != nil {
+			t.Errorf("cancelled request got response %+v", response)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled request didn't return")
+	}
+}
+
+func TestHandleRequestCancelOtherSession(t *testing.T) {
+	s, _ := newTestServer(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s.RegisterTool(NewTool(Tool{Name: "wait", Description: "Wait until released"}, func(ctx context.Context, _ noArgs) (struct{}, error) {
+		close(started)
+		select {
+		case <-release:
+			return struct{}{}, nil
+		case <-ctx.Done():
+			return struct{}{}, ctx.Err()
+		}
+	}))
+
+	ctx := WithSession(context.Background(), s.NewSession(context.Background()))
+	other := WithSession(context.Background(), s.NewSession(context.Background()))
+	responses := make(chan *MCPResponse, 1)
+	go func() {
+		responses <- s.HandleRequest(ctx, newRequest(t, 7, "tools/call", map[string]interface{}{"name": "wait"}))
+	}()
+	<-started
+
+	// Request IDs are only unique within a session
+	s.HandleRequest(other, newRequest(t, nil, "notifications/cancelled", map[string]interface{}{"requestId": 7}))
+	close(release)
+	if response := <-responses; response == nil || response.Error != nil {
+		t.Errorf("got %+v, want a result", response)
+	}
+}
+
+type waitArgs struct {
+	UntilCancelled bool `json:"until_cancelled"`
+}
+
+func TestHandleRequestCancelSessionless(t *testing.T) {
+	s, _ := newTestServer(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s.RegisterTool(NewTool(Tool{Name: "wait", Description: "Wait until released or cancelled"}, func(ctx context.Context, args waitArgs) (struct{}, error) {
+		started <- struct{}{}
+		if args.UntilCancelled {
+			<-ctx.Done()
+			return struct{}{}, ctx.Err()
+		}
+		<-release
+		return struct{}{}, nil
+	}))
+
+	ctx := scopedContext(t, s, config.APIKeyConfig{Name: "first", Tools: []string{"wait"}})
+	other := scopedContext(t, s, config.APIKeyConfig{Name: "second", Tools: []string{"wait"}})
+	call := func(untilCancelled bool) <-chan *MCPResponse {
+		responses := make(chan *MCPResponse, 1)
+		req := newRequest(t, 7, "tools/call", map[string]interface{}{
+			"name":      "wait",
+			"arguments": map[string]interface{}{"until_cancelled": untilCancelled},
+		})
+		go func() {
+			responses <- s.HandleRequest(ctx, req)
+		}()
+		<-started
+		return responses
+	}
+	cancel := newRequest(t, nil, "notifications/cancelled", map[string]interface{}{"requestId": 7})
+
+	// The same ID is reused before the first request finishes
+	first := call(false)
+	second := call(true)
+	close(release)
+	if response := <-first; response == nil || response.Error != nil {
+		t.Fatalf("got %+v, want a result", response)
+	}
+
+	// Other keys, and clients without one, can't cancel it
+	s.HandleRequest(other, cancel)
+	s.HandleRequest(context.Background(), cancel)
+	select {
+	case response := <-second:
+		t.Fatalf("request ended early with %+v", response)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.HandleRequest(ctx, cancel)
+	select {
+	case response := <-second:
+		if response != nil {
+			t.Errorf("cancelled request got response %+v", response)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled request didn't return")
+	}
+}
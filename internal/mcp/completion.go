@@ -0,0 +1,372 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/spotify"
+)
+
+const (
+	// completionDebounce delays Spotify lookups so that a host sending a
+	// request per keystroke only searches for the latest value.
+	completionDebounce = 150 * time.Millisecond
+	completionCacheTTL = time.Minute
+	maxCompletionCache = 512
+	maxCompletions     = 100
+	// completionSearchLimit bounds search-backed suggestions
+	completionSearchLimit = 10
+	// maxListedPlaylists bounds how many of the user's playlists are
+	// fetched for suggestions
+	maxListedPlaylists = 200
+)
+
+// Completion sources, used by prompt arguments' complete or resolve fields
+const (
+	completeArtist   = "artist"
+	completeTrack    = "track"
+	completePlaylist = "playlist"
+	completeDevice   = "device"
+	completeCategory = "category"
+)
+
+// templateCompletions maps resource templates to the source completing
+// their {id} argument.
+var templateCompletions = map[string]string{
+	"spotify://track/{id}":             completeTrack,
+	"spotify://artist/{id}":            completeArtist,
+	"spotify://playlist/{id}":          completePlaylist,
+	"spotify://browse/categories/{id}": completeCategory,
+}
+
+// completionItem is a suggestion; prompt arguments receive the name and
+// resource template IDs the ID.
+type completionItem struct {
+	ID   string
+	Name string
+}
+
+type completionEntry struct {
+	items     []completionItem
+	expiresAt time.Time
+}
+
+// completer debounces and caches completion lookups.
+type completer struct {
+	mu     sync.Mutex
+	latest map[string]uint64
+	seq    uint64
+	cache  map[string]completionEntry
+}
+
+func newCompleter() *completer {
+	return &completer{
+		latest: make(map[string]uint64),
+		cache:  make(map[string]completionEntry),
+	}
+}
+
+func (s *Server) handleComplete(ctx context.Context, req *MCPRequest) *MCPResponse {
+	var params CompleteRequest
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.Argument.Name == "" {
+		return &MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &MCPError{
+				Code:    ErrorCodeInvalidParams,
+				Message: "Invalid parameters",
+			},
+		}
+	}
+
+	source, useIDs, err := s.completionSource(ctx, params.Ref, params.Argument.Name)
+	if err != nil {
+		return &MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &MCPError{
+				Code:    ErrorCodeInvalidParams,
+				Message: err.Error(),
+			},
+		}
+	}
+
+	completion := Completion{Values: []string{}}
+	if source != "" && groupAllowed(ctx, sourceGroups[source]) {
+		// Superseded requests and failed lookups get no suggestions rather
+		// than an error, so hosts just keep the previous list
+		debounceKey := completionClient(ctx) + " " + params.Ref.Type + " " + params.Ref.Name + params.Ref.URI + " " + params.Argument.Name
+		items, err := s.completions.lookup(ctx, debounceKey, source, params.Argument.Value, s.completionFetcher(source))
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).Debugf("Completion for %s failed", source)
+		}
+		completion = newCompletion(items, useIDs)
+	}
+
+	return &MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  CompleteResponse{Completion: completion},
+	}
+}
+
+// completionClient identifies who is typing, so that clients only
+// supersede their own lookups: the session, or else the API key.
+func completionClient(ctx context.Context) string {
+	if session := SessionFromContext(ctx); session != nil {
+		return "session:" + session.ID
+	}
+	if scope := keyScopeFromContext(ctx); scope != nil {
+		return "key:" + scope.Name()
+	}
+	return ""
+}
+
+// completionSource picks the lookup for an argument. Arguments without one
+// complete to nothing; unknown prompts and templates, and those the
+// client's API key doesn't cover, are errors.
+func (s *Server) completionSource(ctx context.Context, ref CompletionReference, argument string) (source string, useIDs bool, err error) {
+	switch ref.Type {
+	case "ref/prompt":
+		prompt, ok := s.prompts[ref.Name]
+		if !ok || !promptAllowed(ctx, prompt) {
+			return "", false, fmt.Errorf("unknown prompt: %s", ref.Name)
+		}
+		for _, arg := range prompt.Arguments {
+			if arg.Name == argument {
+				if arg.Complete != "" {
+					return arg.Complete, false, nil
+				}
+				return arg.Resolve, false, nil
+			}
+		}
+		return "", false, nil
+	case "ref/resource":
+		for _, template := range resourceTemplates {
+			if template.URITemplate == ref.URI && resourceAllowed(ctx, ref.URI) {
+				if argument != "id" {
+					return "", false, nil
+				}
+				return templateCompletions[ref.URI], true, nil
+			}
+		}
+		return "", false, fmt.Errorf("unknown resource template: %s", ref.URI)
+	default:
+		return "", false, fmt.Errorf("unknown reference type: %s", ref.Type)
+	}
+}
+
+// completionFetcher returns the lookup for a source. Search sources take the
+// typed value as the query; list sources fetch everything once and are
+// filtered locally.
+func (s *Server) completionFetcher(source string) func(ctx context.Context, query string) ([]completionItem, error) {
+	switch source {
+	case completeArtist:
+		return func(ctx context.Context, query string) ([]completionItem, error) {
+			result, err := s.spotifyClient.SearchArtists(ctx, query, completionSearchLimit, "")
+			if err != nil {
+				return nil, err
+			}
+			items := make([]completionItem, len(result.Artists))
+			for i, artist := range result.Artists {
+				items[i] = completionItem{ID: artist.ID, Name: artist.Name}
+			}
+			return items, nil
+		}
+	case completeTrack:
+		return func(ctx context.Context, query string) ([]completionItem, error) {
+			result, err := s.spotifyClient.SearchTracks(ctx, query, completionSearchLimit, "")
+			if err != nil {
+				return nil, err
+			}
+			items := make([]completionItem, len(result.Tracks))
+			for i, track := range result.Tracks {
+				items[i] = completionItem{ID: track.ID, Name: track.Name}
+			}
+			return items, nil
+		}
+	case completePlaylist:
+		return func(ctx context.Context, _ string) ([]completionItem, error) {
+			var items []completionItem
+			for offset := 0; offset < maxListedPlaylists; {
+				page, err := s.spotifyClient.GetMyPlaylists(ctx, 50, offset)
+				if err != nil {
+					return nil, err
+				}
+				for _, playlist := range page.Playlists {
+					items = append(items, completionItem{ID: playlist.ID, Name: playlist.Name})
+				}
+				offset = page.Offset + page.Limit
+				if page.Limit <= 0 || offset >= page.Total {
+					break
+				}
+			}
+			return items, nil
+		}
+	case completeDevice:
+		return func(ctx context.Context, _ string) ([]completionItem, error) {
+			devices, err := s.spotifyClient.GetDevices(ctx)
+			if err != nil {
+				return nil, s.playbackError(ctx, err)
+			}
+			items := make([]completionItem, len(devices))
+			for i, device := range devices {
+				items[i] = completionItem{ID: device.ID, Name: device.Name}
+			}
+			return items, nil
+		}
+	case completeCategory:
+		return s.completionCategories
+	}
+	return nil
+}
+
+func (s *Server) completionCategories(ctx context.Context, _ string) ([]completionItem, error) {
+	page, err := s.spotifyClient.GetCategories(ctx, spotify.BrowseOptions{Limit: 50})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]completionItem, len(page.Categories))
+	for i, category := range page.Categories {
+		items[i] = completionItem{ID: category.ID, Name: category.Name}
+	}
+	return items, nil
+}
+
+// isListSource reports whether a source fetches a full list rather than
+// searching for the typed value.
+func isListSource(source string) bool {
+	return source == completePlaylist || source == completeDevice || source == completeCategory
+}
+
+// lookup returns suggestions for value. Cached results are returned at
+// once; otherwise the lookup waits out the debounce window and is dropped
+// if a newer request for the same argument arrived meanwhile.
+func (c *completer) lookup(ctx context.Context, debounceKey, source, value string, fetch func(context.Context, string) ([]completionItem, error)) ([]completionItem, error) {
+	if fetch == nil {
+		return nil, nil
+	}
+
+	query := strings.ToLower(strings.TrimSpace(value))
+	cacheKey := source + "\x00" + query
+	if isListSource(source) {
+		cacheKey = source
+	} else if query == "" {
+		// Search needs something to search for
+		return nil, nil
+	}
+
+	if items, ok := c.cached(cacheKey); ok {
+		return filterCompletions(items, source, query), nil
+	}
+
+	seq := c.begin(debounceKey)
+	select {
+	case <-time.After(completionDebounce):
+	case <-ctx.Done():
+		// Forget the request unless a newer one has replaced it
+		c.isLatest(debounceKey, seq)
+		return nil, ctx.Err()
+	}
+	if !c.isLatest(debounceKey, seq) {
+		return nil, nil
+	}
+
+	items, err := fetch(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	c.store(cacheKey, items)
+	return filterCompletions(items, source, query), nil
+}
+
+func (c *completer) begin(key string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	c.latest[key] = c.seq
+	return c.seq
+}
+
+// isLatest reports whether seq is the newest request for key, and if so
+// forgets it.
+func (c *completer) isLatest(key string, seq uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.latest[key] != seq {
+		return false
+	}
+	delete(c.latest, key)
+	return true
+}
+
+func (c *completer) cached(key string) ([]completionItem, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.cache[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.items, true
+}
+
+func (c *completer) store(key string, items []completionItem) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.cache) >= maxCompletionCache {
+		for k, entry := range c.cache {
+			if now.After(entry.expiresAt) {
+				delete(c.cache, k)
+			}
+		}
+		// Still full of live entries: start over rather than track recency
+		if len(c.cache) >= maxCompletionCache {
+			c.cache = make(map[string]completionEntry)
+		}
+	}
+	c.cache[key] = completionEntry{items: items, expiresAt: now.Add(completionCacheTTL)}
+}
+
+// filterCompletions narrows list sources to names containing the query;
+// search results are already matched by Spotify.
+func filterCompletions(items []completionItem, source, query string) []completionItem {
+	if !isListSource(source) || query == "" {
+		return items
+	}
+	var matched []completionItem
+	for _, item := range items {
+		if strings.Contains(strings.ToLower(item.Name), query) {
+			matched = append(matched, item)
+		}
+	}
+	return matched
+}
+
+func newCompletion(items []completionItem, useIDs bool) Completion {
+	values := make([]string, 0, min(len(items), maxCompletions))
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		value := item.Name
+		if useIDs {
+			value = item.ID
+		}
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		values = append(values, value)
+	}
+
+	completion := Completion{Values: values, Total: len(values)}
+	if len(values) > maxCompletions {
+		completion.Values = values[:maxCompletions]
+		completion.HasMore = true
+	}
+	return completion
+}
@@ -0,0 +1,33 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCompleterForgetsCancelledLookups(t *testing.T) {
+	c := newCompleter()
+	fetch := func(context.Context, string) ([]completionItem, error) {
+		t.Error("a cancelled lookup was fetched")
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.lookup(ctx, "session/artist", completeArtist, "que", fetch); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if len(c.latest) != 0 {
+		t.Errorf("got debounce entries %v after the lookup was cancelled", c.latest)
+	}
+
+	// A newer request's entry survives an older one giving up
+	seq := c.begin("session/artist")
+	if c.begin("session/artist") == seq || c.isLatest("session/artist", seq) {
+		t.Error("an older request was treated as the latest")
+	}
+	if len(c.latest) != 1 {
+		t.Errorf("got debounce entries %v, want the newer request's", c.latest)
+	}
+}
@@ -0,0 +1,124 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Elicitation fallbacks, for clients that can't be asked to confirm.
+const (
+	fallbackDryRun = "dry_run"
+	fallbackReject = "reject"
+	fallbackAllow  = "allow"
+)
+
+var elicitationFallbacks = []string{fallbackDryRun, fallbackReject, fallbackAllow}
+
+var errConfirmationUnavailable = errors.New("this change needs the user's confirmation, but the client does not support elicitation")
+
+// confirmPolicy is how destructive tools get confirmation.
+type confirmPolicy struct {
+	enabled  bool
+	fallback string
+}
+
+// confirmation is the outcome of asking to apply a change.
+type confirmation int
+
+const (
+	// confirmed: apply the change.
+	confirmed confirmation = iota
+	// declined: the user declined or dismissed the request.
+	declined
+	// unconfirmed: the user couldn't be asked, so describe the change
+	// without applying it.
+	unconfirmed
+)
+
+// ElicitRequest is the params of an elicitation/create request.
+type ElicitRequest struct {
+	Message         string                 `json:"message"`
+	RequestedSchema map[string]interface{} `json:"requestedSchema"`
+}
+
+// ElicitResult is the client's answer to an elicitation/create request.
+// Action is accept, decline or cancel; Content is only set on accept.
+type ElicitResult struct {
+	Action  string                 `json:"action"`
+	Content map[string]interface{} `json:"content,omitempty"`
+}
+
+// confirmSchema asks for a single confirmation checkbox.
+var confirmSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"confirm": map[string]interface{}{
+			"type":        "boolean",
+			"title":       "Apply this change",
+			"description": "Check to apply the change",
+		},
+	},
+	"required": []string{"confirm"},
+}
+
+// confirmChange asks the user, through the client, to confirm the change
+// summary describes. Clients that didn't declare elicitation, or have no
+// open stream to receive the request on, get the configured fallback.
+func (s *Server) confirmChange(ctx context.Context, summary string) (confirmation, error) {
+	s.accessMu.RLock()
+	policy := s.confirm
+	s.accessMu.RUnlock()
+	if !policy.enabled {
+		return confirmed, nil
+	}
+
+	session := SessionFromContext(ctx)
+	if session != nil {
+		session.mu.Lock()
+		elicitation := session.elicitation
+		session.mu.Unlock()
+
+		if elicitation {
+			raw, err := s.call(ctx, session, "elicitation/create", &ElicitRequest{
+				Message:         summary,
+				RequestedSchema: confirmSchema,
+			})
+			switch {
+			case err == nil:
+				return parseConfirmation(raw)
+			case !errors.Is(err, errNoStream):
+				return declined, fmt.Errorf("failed to ask for confirmation: %w", err)
+			}
+		}
+	}
+
+	switch policy.fallback {
+	case fallbackAllow:
+		return confirmed, nil
+	case fallbackReject:
+		return declined, errConfirmationUnavailable
+	default:
+		return unconfirmed, nil
+	}
+}
+
+func parseConfirmation(raw json.RawMessage) (confirmation, error) {
+	var result ElicitResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return declined, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	if result.Action == "accept" && result.Content["confirm"] == true {
+		return confirmed, nil
+	}
+	return declined, nil
+}
+
+func validateFallback(fallback string) error {
+	if !slices.Contains(elicitationFallbacks, fallback) {
+		return fmt.Errorf("unknown elicitation fallback %q, expected one of %v", fallback, elicitationFallbacks)
+	}
+	return nil
+}
@@ -0,0 +1,383 @@
+package mcp
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+	"github.com/anuragkothare/spotify_mcp_server/internal/spotify"
+)
+
+// defaultJournalEntries bounds the journal when the config doesn't.
+const defaultJournalEntries = 500
+
+// Kinds of inverse operation.
+const (
+	operationReplace = "replace"
+	operationReorder = "reorder"
+	operationInsert  = "insert"
+	operationRemove  = "remove"
+)
+
+// Change is an applied change to Spotify, as recorded in the journal.
+type Change struct {
+	ID           int64     `json:"id"`
+	Time         time.Time `json:"time"`
+	Tool         string    `json:"tool"`
+	PlaylistID   string    `json:"playlist_id"`
+	PlaylistName string    `json:"playlist_name,omitempty"`
+	Summary      string    `json:"summary"`
+	// SnapshotBefore is the playlist's version the change was applied to.
+	SnapshotBefore string `json:"snapshot_before,omitempty"`
+	// SnapshotAfter is the version the playlist must be at for the change
+	// to be undone without force: the one the change produced, or a later
+	// one an undo of a newer change restored the same items at.
+	SnapshotAfter string `json:"snapshot_after,omitempty"`
+	// Inverse reverts the change; undo entries have none.
+	Inverse  *Operation `json:"inverse,omitempty"`
+	Undoable bool       `json:"undoable"`
+	// UndoneBy is the ID of the entry that undid the change, and Undoes the
+	// ID of the change an undo entry undid.
+	UndoneBy int64 `json:"undone_by,omitempty"`
+	Undoes   int64 `json:"undoes,omitempty"`
+}
+
+// Operation is a change to a playlist's items that the journal can replay.
+type Operation struct {
+	Kind string `json:"kind" jsonschema:"enum=replace|reorder|insert|remove"`
+	// URIs are the playlist's items for a replace
+	URIs    []string      `json:"uris,omitempty"`
+	Reorder *ReorderRange `json:"reorder,omitempty"`
+	// Items are the items to insert, at their positions after the insert,
+	// or to remove, at their positions before the removal; both ascending
+	Items []PlaylistItemChange `json:"items,omitempty"`
+}
+
+// ReorderRange moves RangeLength items from RangeStart to before
+// InsertBefore, both counted before the move.
+type ReorderRange struct {
+	RangeStart   int `json:"range_start"`
+	RangeLength  int `json:"range_length"`
+	InsertBefore int `json:"insert_before"`
+}
+
+// inverse returns the move that puts the items back.
+func (r ReorderRange) inverse() ReorderRange {
+	if r.InsertBefore > r.RangeStart {
+		return ReorderRange{
+			RangeStart:   r.InsertBefore - r.RangeLength,
+			RangeLength:  r.RangeLength,
+			InsertBefore: r.RangeStart,
+		}
+	}
+	return ReorderRange{
+		RangeStart:   r.InsertBefore,
+		RangeLength:  r.RangeLength,
+		InsertBefore: r.RangeStart + r.RangeLength,
+	}
+}
+
+var errChangeBusy = errors.New("the change is already being undone")
+
+// journal is the list of applied changes, oldest first. It is rewritten
+// whole on every change when it has a path, so it survives restarts.
+type journal struct {
+	mu      sync.Mutex
+	path    string
+	max     int
+	nextID  int64
+	changes []*Change
+	// undoing holds the changes with an undo in progress
+	undoing map[int64]bool
+}
+
+// journalFile is the journal's on-disk form.
+type journalFile struct {
+	NextID  int64     `json:"next_id"`
+	Changes []*Change `json:"changes"`
+}
+
+func newJournal(path string, max int) *journal {
+	if max <= 0 {
+		max = defaultJournalEntries
+	}
+	return &journal{path: path, max: max, nextID: 1, undoing: make(map[int64]bool)}
+}
+
+// OpenJournal loads the change journal from its configured path, creating
+// it on the first change. Without a path the journal is only kept in
+// memory.
+func (s *Server) OpenJournal(cfg config.JournalConfig) error {
+	j := newJournal(cfg.Path, cfg.MaxEntries)
+	if j.path != "" {
+		if err := j.load(); err != nil {
+			return err
+		}
+	}
+
+	s.journalMu.Lock()
+	s.journal = j
+	s.journalMu.Unlock()
+	return nil
+}
+
+func (s *Server) changeJournal() *journal {
+	s.journalMu.RLock()
+	defer s.journalMu.RUnlock()
+	return s.journal
+}
+
+func (j *journal) load() error {
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create journal dir: %w", err)
+	}
+	data, err := os.ReadFile(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	var file journalFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse journal %s: %w", j.path, err)
+	}
+	j.changes = file.Changes
+	j.nextID = max(file.NextID, 1)
+	j.trim()
+	return nil
+}
+
+// save rewrites the journal file; the caller holds mu.
+func (j *journal) save() error {
+	if j.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(journalFile{NextID: j.nextID, Changes: j.changes}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// trim drops the oldest changes beyond the limit.
+func (j *journal) trim() {
+	if extra := len(j.changes) - j.max; extra > 0 {
+		j.changes = slices.Delete(j.changes, 0, extra)
+	}
+}
+
+// record adds an applied change and returns its ID. When the change is an
+// undo, the change it undid is marked, and any earlier change whose items
+// the undo restored now expects the undo's snapshot. A failure to save is
+// returned, but the change stays recorded in memory.
+func (j *journal) record(change Change) (int64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	change.ID = j.nextID
+	change.Undoable = change.Inverse != nil
+	j.nextID++
+
+	if undone := j.find(change.Undoes); undone != nil {
+		undone.UndoneBy = change.ID
+		undone.Undoable = false
+		for _, earlier := range slices.Backward(j.changes) {
+			if earlier.PlaylistID == undone.PlaylistID && earlier.Undoable &&
+				earlier.SnapshotAfter == undone.SnapshotBefore {
+				earlier.SnapshotAfter = change.SnapshotAfter
+				break
+			}
+		}
+	}
+
+	j.changes = append(j.changes, &change)
+	j.trim()
+	return change.ID, j.save()
+}
+
+// recent returns up to limit changes, newest first, optionally only those
+// to one playlist.
+func (j *journal) recent(limit int, playlistID string) []Change {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	changes := []Change{}
+	for _, change := range slices.Backward(j.changes) {
+		if len(changes) == limit {
+			break
+		}
+		if playlistID == "" || change.PlaylistID == playlistID {
+			changes = append(changes, *change)
+		}
+	}
+	return changes
+}
+
+// claim returns a copy of a change that is about to be undone, and keeps
+// other undos of it out until release.
+func (j *journal) claim(id int64) (Change, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	change := j.find(id)
+	switch {
+	case change == nil:
+		return Change{}, fmt.Errorf("%w: change %d is not in the journal", errInvalidArguments, id)
+	case change.UndoneBy != 0:
+		return Change{}, fmt.Errorf("%w: change %d was already undone by change %d", errInvalidArguments, id, change.UndoneBy)
+	case change.Inverse == nil:
+		return Change{}, fmt.Errorf("%w: change %d can't be undone", errInvalidArguments, id)
+	case j.undoing[id]:
+		return Change{}, fmt.Errorf("%w: change %d", errChangeBusy, id)
+	}
+	j.undoing[id] = true
+	return *change, nil
+}
+
+func (j *journal) release(id int64) {
+	j.mu.Lock()
+	delete(j.undoing, id)
+	j.mu.Unlock()
+}
+
+// find returns the change with id; the caller holds mu.
+func (j *journal) find(id int64) *Change {
+	if id == 0 {
+		return nil
+	}
+	i, found := slices.BinarySearchFunc(j.changes, id, func(change *Change, id int64) int {
+		return cmp.Compare(change.ID, id)
+	})
+	if !found {
+		return nil
+	}
+	return j.changes[i]
+}
+
+var errUndoConflict = errors.New("the playlist changed since")
+
+type listRecentChangesArgs struct {
+	Limit      int    `json:"limit,omitempty" description:"Maximum number of changes to return" jsonschema:"minimum=1,maximum=100,default=20"`
+	PlaylistID string `json:"playlist_id,omitempty" description:"Only list changes to this playlist"`
+}
+
+type undoChangeArgs struct {
+	ChangeID int64 `json:"change_id" description:"ID of the change to undo, from list_recent_changes or the change_id of an edit" jsonschema:"required,minimum=1"`
+	Force    bool  `json:"force,omitempty" description:"Undo even though the playlist changed since; a restored playlist loses the later changes" jsonschema:"default=false"`
+	dryRunArg
+}
+
+// RecentChanges lists journaled changes, newest first.
+type RecentChanges struct {
+	Changes []Change `json:"changes"`
+}
+
+func (s *Server) handleListRecentChanges(ctx context.Context, args listRecentChangesArgs) (*RecentChanges, error) {
+	return &RecentChanges{Changes: s.changeJournal().recent(args.Limit, args.PlaylistID)}, nil
+}
+
+// handleUndoChange replays a change's inverse, provided the playlist is
+// still at the version the change left it at. The undo goes through the
+// same confirmation and dry run handling as the edit it reverts.
+func (s *Server) handleUndoChange(ctx context.Context, args undoChangeArgs) (*PlaylistEditResult, error) {
+	j := s.changeJournal()
+	change, err := j.claim(args.ChangeID)
+	if err != nil {
+		return nil, err
+	}
+	defer j.release(change.ID)
+
+	current, err := s.spotifyClient.GetPlaylistItems(ctx, change.PlaylistID)
+	if err != nil {
+		return nil, err
+	}
+	if current.SnapshotID != change.SnapshotAfter && !args.Force {
+		return nil, fmt.Errorf("%w change %d: playlist %q is at snapshot %s, not %s; undo the newer changes first, or pass force to undo anyway",
+			errUndoConflict, change.ID, current.Name, current.SnapshotID, change.SnapshotAfter)
+	}
+
+	var edit *playlistEdit
+	op := change.Inverse
+	switch op.Kind {
+	case operationReorder:
+		move := *op.Reorder
+		if err := validateMove(move, len(current.Items)); err != nil {
+			return nil, fmt.Errorf("%w change %d: the items it moved are gone", errUndoConflict, change.ID)
+		}
+		edit = reorderEdit(current, move)
+		// Positions are relative to the version the change left, which
+		// Spotify can resolve even if the playlist changed since
+		edit.apply = func(ctx context.Context) (string, error) {
+			return s.spotifyClient.ReorderPlaylistItems(ctx, change.PlaylistID, move.RangeStart, move.RangeLength, move.InsertBefore, change.SnapshotAfter)
+		}
+	case operationRemove:
+		if edit = removeEdit(current, op.Items); edit == nil {
+			return nil, fmt.Errorf("%w change %d: the items it added are gone", errUndoConflict, change.ID)
+		}
+		positions := make([]spotify.PlaylistItemPosition, len(op.Items))
+		for i, item := range op.Items {
+			positions[i] = spotify.PlaylistItemPosition{URI: item.URI, Position: item.Position}
+		}
+		edit.apply = func(ctx context.Context) (string, error) {
+			return s.spotifyClient.RemovePlaylistItemsAt(ctx, change.PlaylistID, positions, change.SnapshotAfter)
+		}
+	case operationInsert:
+		if edit = insertEdit(current, op.Items); edit == nil {
+			return nil, fmt.Errorf("%w change %d: its items' positions are past the end of the playlist", errUndoConflict, change.ID)
+		}
+		edit.apply = func(ctx context.Context) (string, error) {
+			var snapshotID string
+			for _, run := range positionRuns(op.Items) {
+				uris := make([]string, len(run))
+				for i, item := range run {
+					uris[i] = item.URI
+				}
+				var err error
+				if snapshotID, err = s.spotifyClient.AddPlaylistItems(ctx, change.PlaylistID, uris, &run[0].Position); err != nil {
+					return "", err
+				}
+			}
+			return snapshotID, nil
+		}
+	case operationReplace:
+		after := itemsFor(op.URIs, current.Items)
+		edit = &playlistEdit{
+			before:      current,
+			after:       after,
+			removed:     itemChanges(current.Items, 0),
+			added:       itemChanges(after, 0),
+			destructive: true,
+			summary:     fmt.Sprintf("Restore the %d items playlist %q had before", len(op.URIs), current.Name),
+			apply: func(ctx context.Context) (string, error) {
+				return s.spotifyClient.ReplacePlaylistItems(ctx, change.PlaylistID, op.URIs)
+			},
+		}
+	default:
+		return nil, fmt.Errorf("change %d has an unknown inverse %q", change.ID, op.Kind)
+	}
+
+	// Undos are journaled without an inverse, so they can't be undone
+	edit.inverse = nil
+	edit.tool = "undo_change"
+	edit.undoes = change.ID
+	edit.summary = fmt.Sprintf("Undo change %d (%s). %s", change.ID, change.Tool, edit.summary)
+	return s.runEdit(ctx, edit, args.DryRun)
+}
@@ -0,0 +1,142 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func undo(t *testing.T, s *Server, args map[string]interface{}) *MCPResponse {
+	t.Helper()
+	return callTool(t, s, context.Background(), "undo_change", args)
+}
+
+func TestUndoAdd(t *testing.T) {
+	s, fake := newTestServer(t)
+
+	// The added copy of an item already in the playlist is the one removed
+	added := editPlaylist(t, s, context.Background(), "add_playlist_items", map[string]interface{}{
+		"playlist_id": roadTripID,
+		"uris":        []string{trackB},
+		"position":    0,
+	})
+	expectItems(t, fake, roadTripID, trackB, trackA, trackB)
+
+	result := toolOutput[PlaylistEditResult](t, undo(t, s, map[string]interface{}{"change_id": added.ChangeID}))
+	if result.Status != editApplied {
+		t.Fatalf("got %+v", result)
+	}
+	expectItems(t, fake, roadTripID, trackA, trackB)
+
+	changes := toolOutput[RecentChanges](t, callTool(t, s, context.Background(), "list_recent_changes", map[string]interface{}{}))
+	if len(changes.Changes) != 2 {
+		t.Fatalf("got %d changes, want 2", len(changes.Changes))
+	}
+	if undoChange, change := changes.Changes[0], changes.Changes[1]; change.UndoneBy != undoChange.ID || undoChange.Undoes != change.ID || change.Undoable {
+		t.Errorf("got changes %+v", changes.Changes)
+	}
+	undoChange := changes.Changes[0]
+	if undoChange.Inverse != nil || undoChange.Undoable {
+		t.Errorf("got undo entry %+v, want it not undoable", undoChange)
+	}
+	err := expectError(t, undo(t, s, map[string]interface{}{"change_id": undoChange.ID}), ErrorCodeInvalidParams)
+	if !strings.Contains(err.Message, "can't be undone") {
+		t.Errorf("got %q", err.Message)
+	}
+
+	err = expectError(t, undo(t, s, map[string]interface{}{"change_id": added.ChangeID}), ErrorCodeInvalidParams)
+	if !strings.Contains(err.Message, "already undone") {
+		t.Errorf("got %q", err.Message)
+	}
+}
+
+func TestUndoRemove(t *testing.T) {
+	s, fake := newTestServer(t)
+
+	editPlaylist(t, s, context.Background(), "add_playlist_items", map[string]interface{}{
+		"playlist_id": roadTripID,
+		"uris":        []string{trackA},
+	})
+	removed := editPlaylist(t, s, context.Background(), "remove_playlist_items", map[string]interface{}{
+		"playlist_id": roadTripID,
+		"uris":        []string{trackA},
+	})
+	expectItems(t, fake, roadTripID, trackB)
+
+	// Each occurrence goes back where it was
+	undo(t, s, map[string]interface{}{"change_id": removed.ChangeID})
+	expectItems(t, fake, roadTripID, trackA, trackB, trackA)
+}
+
+func TestUndoReorder(t *testing.T) {
+	s, fake := newTestServer(t)
+
+	moved := editPlaylist(t, s, context.Background(), "reorder_playlist_items", map[string]interface{}{
+		"playlist_id":   roadTripID,
+		"range_start":   0,
+		"insert_before": 2,
+	})
+	expectItems(t, fake, roadTripID, trackB, trackA)
+
+	undo(t, s, map[string]interface{}{"change_id": moved.ChangeID})
+	expectItems(t, fake, roadTripID, trackA, trackB)
+}
+
+func TestUndoReplace(t *testing.T) {
+	s, fake := newTestServer(t)
+
+	replaced := editPlaylist(t, s, context.Background(), "replace_playlist_items", map[string]interface{}{
+		"playlist_id": roadTripID,
+		"uris":        []string{},
+	})
+	expectItems(t, fake, roadTripID)
+
+	undo(t, s, map[string]interface{}{"change_id": replaced.ChangeID})
+	expectItems(t, fake, roadTripID, trackA, trackB)
+}
+
+func TestUndoConflict(t *testing.T) {
+	s, fake := newTestServer(t)
+
+	added := editPlaylist(t, s, context.Background(), "add_playlist_items", map[string]interface{}{
+		"playlist_id": roadTripID,
+		"uris":        []string{trackC},
+	})
+	if _, err := fake.AddPlaylistItems(context.Background(), roadTripID, []string{trackA}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	err := expectError(t, undo(t, s, map[string]interface{}{"change_id": added.ChangeID}), ErrorCodeInternalError)
+	if !strings.Contains(err.Message, errUndoConflict.Error()) {
+		t.Errorf("got %q, want an undo conflict", err.Message)
+	}
+	expectItems(t, fake, roadTripID, trackA, trackB, trackC, trackA)
+
+	dryRun := toolOutput[PlaylistEditResult](t, undo(t, s, map[string]interface{}{"change_id": added.ChangeID, "force": true, "dry_run": true}))
+	if dryRun.Status != editDryRun || len(dryRun.Calls) != 1 {
+		t.Errorf("got %+v, want a dry run with one call", dryRun)
+	}
+	expectItems(t, fake, roadTripID, trackA, trackB, trackC, trackA)
+
+	// The later edit survives a forced undo
+	undo(t, s, map[string]interface{}{"change_id": added.ChangeID, "force": true})
+	expectItems(t, fake, roadTripID, trackA, trackB, trackA)
+}
+
+func TestUndoItemsGone(t *testing.T) {
+	s, fake := newTestServer(t)
+
+	added := editPlaylist(t, s, context.Background(), "add_playlist_items", map[string]interface{}{
+		"playlist_id": roadTripID,
+		"uris":        []string{trackC},
+	})
+	if _, err := fake.RemovePlaylistItems(context.Background(), roadTripID, []string{trackC}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	err := expectError(t, undo(t, s, map[string]interface{}{"change_id": added.ChangeID, "force": true}), ErrorCodeInternalError)
+	if !strings.Contains(err.Message, "the items it added are gone") {
+		t.Errorf("got %q", err.Message)
+	}
+	expectItems(t, fake, roadTripID, trackA, trackB)
+}
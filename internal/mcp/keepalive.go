@@ -0,0 +1,89 @@
+package mcp
+
+import (
+	"context"
+	"time"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+)
+
+// RunKeepalive pings sessions with an open stream once they have been idle
+// for the keepalive interval, and closes those that don't answer within
+// the timeout. Sessions without a stream can't be pinged and are closed
+// after the idle timeout instead. It returns when ctx is done.
+func (s *Server) RunKeepalive(ctx context.Context, cfg config.KeepaliveConfig) {
+	if cfg.Interval <= 0 {
+		return
+	}
+	interval := time.Duration(cfg.Interval) * time.Second
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	idleTimeout := time.Duration(cfg.IdleTimeout) * time.Second
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkSessions(ctx, interval, timeout, idleTimeout)
+		}
+	}
+}
+
+func (s *Server) checkSessions(ctx context.Context, interval, timeout, idleTimeout time.Duration) {
+	s.sessionsMu.Lock()
+	sessions := make([]*Session, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	s.sessionsMu.Unlock()
+
+	for _, session := range sessions {
+		session.mu.Lock()
+		idle := time.Since(session.lastSeen)
+		streaming, pinging := session.streaming, session.pinging
+		if streaming && !pinging && idle >= interval {
+			session.pinging = true
+		}
+		session.mu.Unlock()
+
+		switch {
+		case streaming && !pinging && idle >= interval:
+			go s.pingSession(ctx, session, timeout)
+		case !streaming && idleTimeout > 0 && idle >= idleTimeout:
+			s.reapSession(session, "idle for "+idle.Round(time.Second).String())
+		}
+	}
+}
+
+func (s *Server) pingSession(ctx context.Context, session *Session, timeout time.Duration) {
+	defer func() {
+		session.mu.Lock()
+		session.pinging = false
+		session.mu.Unlock()
+	}()
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	_, err := s.call(ctx, session, "ping", nil)
+	switch {
+	case err == nil:
+		session.touch()
+	case err == errSessionClosed, err == context.Canceled:
+		// Closed meanwhile, or the server is shutting down
+	default:
+		s.reapSession(session, "no answer to ping: "+err.Error())
+	}
+}
+
+func (s *Server) reapSession(session *Session, reason string) {
+	if s.CloseSession(session.ID) {
+		s.logger.Infof("Closed session %s: %s", session.ID, reason)
+	}
+}
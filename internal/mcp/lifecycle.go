@@ -0,0 +1,73 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+)
+
+const (
+	serverName    = "spotify-mcp-server"
+	serverVersion = "1.0.0"
+)
+
+// supportedProtocolVersions lists the MCP revisions the server speaks,
+// newest first.
+var supportedProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// handleInitialize negotiates the protocol version and advertises the
+// server's capabilities. The HTTP layer creates the session beforehand.
+func (s *Server) handleInitialize(ctx context.Context, req *MCPRequest) *MCPResponse {
+	var params InitializeRequest
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return &MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &MCPError{
+				Code:    ErrorCodeInvalidParams,
+				Message: "Invalid parameters",
+			},
+		}
+	}
+
+	// Answer with the client's version when supported, otherwise our latest
+	// and let the client decide whether to continue
+	version := supportedProtocolVersions[0]
+	for _, supported := range supportedProtocolVersions {
+		if params.ProtocolVersion == supported {
+			version = supported
+			break
+		}
+	}
+
+	if session := SessionFromContext(ctx); session != nil {
+		session.ClientInfo = params.ClientInfo
+		session.mu.Lock()
+		_, session.elicitation = params.Capabilities["elicitation"]
+		session.mu.Unlock()
+		s.logger.WithContext(ctx).Infof("Session %s initialized by %s %s (protocol %s)",
+			session.ID, params.ClientInfo.Name, params.ClientInfo.Version, version)
+	}
+
+	return &MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: InitializeResponse{
+			ProtocolVersion: version,
+			Capabilities:    s.capabilities(),
+			ServerInfo: ServerInfo{
+				Name:    serverName,
+				Version: serverVersion,
+			},
+		},
+	}
+}
+
+func (s *Server) capabilities() map[string]interface{} {
+	return map[string]interface{}{
+		"tools":       map[string]interface{}{"listChanged": true},
+		"resources":   map[string]interface{}{},
+		"prompts":     map[string]interface{}{},
+		"completions": map[string]interface{}{},
+		"logging":     map[string]interface{}{},
+	}
+}
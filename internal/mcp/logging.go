@@ -0,0 +1,222 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// logLevels are the MCP (syslog) severities, least severe first.
+var logLevels = []string{"debug", "info", "notice", "warning", "error", "critical", "alert", "emergency"}
+
+// defaultLoggerName names entries that don't carry a "component" field.
+const defaultLoggerName = serverName
+
+// SetLevelRequest represents the params of logging/setLevel
+type SetLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// LoggingMessageNotification represents the params of notifications/message
+type LoggingMessageNotification struct {
+	Level  string      `json:"level"`
+	Logger string      `json:"logger,omitempty"`
+	Data   interface{} `json:"data"`
+}
+
+func logLevelIndex(level string) int {
+	for i, l := range logLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// mcpLogLevel maps a logrus level to an MCP severity.
+func mcpLogLevel(level logrus.Level) string {
+	switch level {
+	case logrus.PanicLevel:
+		return "emergency"
+	case logrus.FatalLevel:
+		return "critical"
+	case logrus.ErrorLevel:
+		return "error"
+	case logrus.WarnLevel:
+		return "warning"
+	case logrus.InfoLevel:
+		return "info"
+	default:
+		return "debug"
+	}
+}
+
+// SetLogLevel sets the minimum severity forwarded to the session. Sessions
+// receive no log messages until a level is set.
+func (sess *Session) SetLogLevel(level string) {
+	sess.mu.Lock()
+	sess.logLevel = logLevelIndex(level) + 1
+	sess.mu.Unlock()
+}
+
+// seesServerLogs reports whether the session may receive log entries that
+// aren't about its own requests: those can concern other clients, so they
+// need a key scoped to the admin group, or a server without API keys.
+func (sess *Session) seesServerLogs() bool {
+	return sess.scope == nil || sess.scope.groups[GroupAdmin]
+}
+
+func (sess *Session) wantsLog(level string) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.logLevel > 0 && logLevelIndex(level) >= sess.logLevel-1
+}
+
+func (s *Server) handleSetLevel(ctx context.Context, req *MCPRequest) *MCPResponse {
+	var params SetLevelRequest
+	if err := json.Unmarshal(req.Params, &params); err != nil || logLevelIndex(params.Level) < 0 {
+		return &MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &MCPError{
+				Code:    ErrorCodeInvalidParams,
+				Message: fmt.Sprintf("Invalid log level, expected one of: %s", strings.Join(logLevels, ", ")),
+			},
+		}
+	}
+
+	session := SessionFromContext(ctx)
+	if session == nil {
+		return &MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &MCPError{
+				Code:    ErrorCodeInvalidRequest,
+				Message: "logging/setLevel requires a session; send the Mcp-Session-Id header from initialize",
+			},
+		}
+	}
+
+	session.SetLogLevel(params.Level)
+	s.logger.WithContext(ctx).Debugf("Session %s log level set to %s", session.ID, params.Level)
+
+	return &MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  map[string]interface{}{},
+	}
+}
+
+// logHook forwards log entries to sessions as notifications/message. An
+// entry logged with a request context goes only to that request's session,
+// if it has one. Other entries are server-wide, and go to the sessions
+// allowed to see them whose level admits them. The hook must not log,
+// since it runs inside the logger.
+type logHook struct {
+	server *Server
+}
+
+func (h *logHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *logHook) Fire(entry *logrus.Entry) error {
+	level := mcpLogLevel(entry.Level)
+
+	var targets []*Session
+	if entry.Context != nil {
+		if session := SessionFromContext(entry.Context); session != nil {
+			targets = []*Session{session}
+		}
+	} else {
+		h.server.sessionsMu.Lock()
+		for _, session := range h.server.sessions {
+			if session.seesServerLogs() {
+				targets = append(targets, session)
+			}
+		}
+		h.server.sessionsMu.Unlock()
+	}
+
+	var message *MCPNotification
+	for _, session := range targets {
+		if !session.wantsLog(level) {
+			continue
+		}
+		if message == nil {
+			message = logNotification(entry, level)
+		}
+		// Dropped when the client isn't reading; logging about that here
+		// would recurse
+		session.Send(message)
+	}
+	return nil
+}
+
+func logNotification(entry *logrus.Entry, level string) *MCPNotification {
+	logger := defaultLoggerName
+	data := map[string]interface{}{
+		"message": redactSecrets(entry.Message),
+	}
+	for key, value := range entry.Data {
+		if key == "component" {
+			logger = fmt.Sprint(value)
+			continue
+		}
+		data[key] = redactField(key, value)
+	}
+
+	return &MCPNotification{
+		JSONRPC: "2.0",
+		Method:  "notifications/message",
+		Params: LoggingMessageNotification{
+			Level:  level,
+			Logger: logger,
+			Data:   data,
+		},
+	}
+}
+
+var (
+	// secretKeyPattern matches field names whose values are credentials.
+	secretKeyPattern = regexp.MustCompile(`(?i)(token|secret|password|authorization|api_?key|^code$)`)
+
+	// secretValuePatterns match credentials embedded in free text: bearer
+	// tokens, key=value pairs in URLs or form bodies, and JSON members.
+	secretValuePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`),
+		regexp.MustCompile(`(?i)((?:access_token|refresh_token|client_secret|api_key|code)=)[^&\s"]+`),
+		regexp.MustCompile(`(?i)("(?:access_token|refresh_token|client_secret|api_key|code)"\s*:\s*")[^"]*`),
+	}
+
+	// sessionIDPattern matches the IDs NewSession generates, which
+	// authenticate a client's requests. Matching their form also catches
+	// the IDs of sessions closed since.
+	sessionIDPattern = regexp.MustCompile(`\b[0-9a-f]{32}\b`)
+)
+
+func redactSecrets(text string) string {
+	for _, pattern := range secretValuePatterns {
+		text = pattern.ReplaceAllString(text, "${1}REDACTED")
+	}
+	return sessionIDPattern.ReplaceAllString(text, "REDACTED")
+}
+
+func redactField(key string, value interface{}) interface{} {
+	if secretKeyPattern.MatchString(key) {
+		return "REDACTED"
+	}
+	switch v := value.(type) {
+	case error:
+		return redactSecrets(v.Error())
+	case string:
+		return redactSecrets(v)
+	case fmt.Stringer:
+		return redactSecrets(v.String())
+	}
+	return value
+}
@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// logMessages drains the log notifications queued for a session.
+func logMessages(t *testing.T, session *Session) []LoggingMessageNotification {
+	t.Helper()
+	var messages []LoggingMessageNotification
+	for {
+		select {
+		case data := <-session.outbox:
+			var notification struct {
+				Method string                     `json:"method"`
+				Params LoggingMessageNotification `json:"params"`
+			}
+			if err := json.Unmarshal(data, &notification); err != nil {
+				t.Fatalf("unmarshal %s: %v", data, err)
+			}
+			if notification.Method == "notifications/message" {
+				messages = append(messages, notification.Params)
+			}
+		default:
+			return messages
+		}
+	}
+}
+
+func TestLogRouting(t *testing.T) {
+	s, _ := newTestServer(t)
+	newSession := func(key *config.APIKeyConfig) *Session {
+		ctx := context.Background()
+		if key != nil {
+			ctx = scopedContext(t, s, *key)
+		}
+		session := s.NewSession(ctx)
+		session.SetLogLevel("info")
+		return session
+	}
+	unscoped := newSession(nil)
+	admin := newSession(&config.APIKeyConfig{Name: "admin", Groups: []string{GroupAdmin}})
+	catalog := newSession(&config.APIKeyConfig{Name: "catalog", Groups: []string{GroupCatalog}})
+	quiet := s.NewSession(context.Background())
+
+	s.logger.Info("server-wide")
+	s.logger.WithContext(WithSession(context.Background(), catalog)).Info("about a request")
+	s.logger.WithContext(context.Background()).Info("about a request without a session")
+	s.logger.Debug("below the level")
+
+	for _, test := range []struct {
+		name    string
+		session *Session
+		want    []string
+	}{
+		{"unscoped", unscoped, []string{"server-wide"}},
+		{"admin", admin, []string{"server-wide"}},
+		{"catalog", catalog, []string{"about a request"}},
+		{"quiet", quiet, nil},
+	} {
+		var got []string
+		for _, message := range logMessages(t, test.session) {
+			data, _ := message.Data.(map[string]interface{})
+			got = append(got, data["message"].(string))
+		}
+		if strings.Join(got, "|") != strings.Join(test.want, "|") {
+			t.Errorf("%s session got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLogRedaction(t *testing.T) {
+	s, _ := newTestServer(t)
+	session := s.NewSession(context.Background())
+	session.SetLogLevel("debug")
+	s.logger.SetLevel(logrus.DebugLevel)
+
+	other := s.NewSession(context.Background())
+	s.logger.WithFields(logrus.Fields{
+		"component":     "spotify",
+		"refresh_token": "r3fresh",
+		"url":           "https://accounts.spotify.com/api/token?code=s3cret&state=1",
+	}).Warnf("Request with Bearer abc.def failed for session %s", other.ID)
+
+	messages := logMessages(t, session)
+	if len(messages) != 1 {
+		t.Fatalf("got %d messages, want 1", len(messages))
+	}
+	message := messages[0]
+	if message.Level != "warning" || message.Logger != "spotify" {
+		t.Errorf("got level %q and logger %q", message.Level, message.Logger)
+	}
+	data, err := json.Marshal(message.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"r3fresh", "s3cret", "abc.def", other.ID} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("%s leaked in %s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), "state=1") {
+		t.Errorf("redaction removed too much: %s", data)
+	}
+}
@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	errNoStream      = errors.New("session has no open stream")
+	errSessionClosed = errors.New("session closed")
+)
+
+// MCPServerRequest is a JSON-RPC request sent by the server to the client.
+type MCPServerRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      string      `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// clientReply is the client's response to a server-initiated request.
+type clientReply struct {
+	Result json.RawMessage
+	Error  *MCPError
+}
+
+// call sends a request to the client over the session's stream and waits
+// for the reply, which the client POSTs back.
+func (s *Server) call(ctx context.Context, session *Session, method string, params interface{}) (json.RawMessage, error) {
+	if !session.isStreaming() {
+		return nil, errNoStream
+	}
+
+	id := fmt.Sprintf("srv-%d", session.nextID.Add(1))
+	key := requestKey(id)
+	reply := make(chan *clientReply, 1)
+
+	session.mu.Lock()
+	session.pending[key] = reply
+	session.mu.Unlock()
+	defer func() {
+		session.mu.Lock()
+		delete(session.pending, key)
+		session.mu.Unlock()
+	}()
+
+	if !session.Send(&MCPServerRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params}) {
+		return nil, fmt.Errorf("failed to send %s: session outbox full", method)
+	}
+
+	select {
+	case r := <-reply:
+		if r.Error != nil {
+			return nil, fmt.Errorf("client rejected %s: %d %s", method, r.Error.Code, r.Error.Message)
+		}
+		return r.Result, nil
+	case <-session.done:
+		return nil, errSessionClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Server) handleReply(ctx context.Context, req *MCPRequest) {
+	session := SessionFromContext(ctx)
+	if session == nil {
+		s.logger.WithContext(ctx).Debugf("Ignoring response %v sent without a session", req.ID)
+		return
+	}
+
+	session.mu.Lock()
+	reply, ok := session.pending[requestKey(req.ID)]
+	session.mu.Unlock()
+	if !ok {
+		s.logger.WithContext(ctx).Debugf("Ignoring response to unknown request %v", req.ID)
+		return
+	}
+
+	// Buffered, and each request gets at most one reply delivered
+	select {
+	case reply <- req.reply:
+	default:
+	}
+}
@@ -0,0 +1,39 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/spotify"
+	zspotify "github.com/zmb3/spotify/v2"
+)
+
+// playbackError explains a Spotify 403 from a playback or device endpoint
+// when the user has no Premium subscription, which Spotify requires there.
+// Other errors, and 403s for Premium users, are returned as they are.
+func (s *Server) playbackError(ctx context.Context, err error) error {
+	var spotifyErr zspotify.Error
+	if !errors.As(err, &spotifyErr) || spotifyErr.Status != http.StatusForbidden {
+		return err
+	}
+	user, userErr := s.spotifyClient.GetCurrentUser(ctx)
+	if userErr != nil || user.IsPremium() {
+		return err
+	}
+	return fmt.Errorf("%w: Spotify only allows playback and device control with a Premium subscription, and this account is on %q", err, user.Product)
+}
+
+// DeviceList is the result of list_devices.
+type DeviceList struct {
+	Devices []spotify.Device `json:"devices"`
+}
+
+func (s *Server) handleListDevices(ctx context.Context, args noArgs) (*DeviceList, error) {
+	devices, err := s.spotifyClient.GetDevices(ctx)
+	if err != nil {
+		return nil, s.playbackError(ctx, err)
+	}
+	return &DeviceList{Devices: devices}, nil
+}
@@ -0,0 +1,53 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+	zspotify "github.com/zmb3/spotify/v2"
+)
+
+func TestPlaybackErrorPremium(t *testing.T) {
+	s, fake := newTestServer(t)
+	forbidden := fmt.Errorf("failed to get devices: %w", zspotify.Error{Status: http.StatusForbidden, Message: "Premium required"})
+
+	// The fixture user has Premium, so the 403 is about something else
+	if err := s.playbackError(context.Background(), forbidden); err != forbidden {
+		t.Errorf("got %v for a Premium user, want the error unchanged", err)
+	}
+
+	fake.Product = "free"
+	err := s.playbackError(context.Background(), forbidden)
+	if !strings.Contains(err.Error(), "Premium subscription") || !strings.Contains(err.Error(), `"free"`) {
+		t.Errorf("got %q, want it to explain that Premium is required", err)
+	}
+	if !errors.Is(err, forbidden) {
+		t.Error("the explanation doesn't wrap the Spotify error")
+	}
+
+	notFound := zspotify.Error{Status: http.StatusNotFound}
+	if err := s.playbackError(context.Background(), notFound); err != error(notFound) {
+		t.Errorf("got %v, want a 404 unchanged", err)
+	}
+}
+
+func TestListDevices(t *testing.T) {
+	s, _ := newTestServer(t)
+	ctx := scopedContext(t, s, config.APIKeyConfig{Name: "player", Groups: []string{GroupPlayback}})
+
+	var list ListToolsResponse
+	decodeResult(t, s.HandleRequest(ctx, newRequest(t, 1, "tools/list", nil)), &list)
+	if len(list.Tools) != 1 || list.Tools[0].Name != "list_devices" {
+		t.Errorf("got tools %+v, want only list_devices", list.Tools)
+	}
+
+	devices := toolOutput[DeviceList](t, callTool(t, s, ctx, "list_devices", map[string]interface{}{}))
+	if len(devices.Devices) == 0 {
+		t.Error("got no devices")
+	}
+}
@@ -0,0 +1,484 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/spotify"
+)
+
+// Outcomes of a playlist edit.
+const (
+	editApplied  = "applied"
+	editDeclined = "declined"
+	editDryRun   = "dry_run"
+)
+
+// confirmTimeout bounds destructive tool calls, which may wait on the user
+// to confirm them.
+const confirmTimeout = 5 * time.Minute
+
+// maxSummaryItems bounds the items listed by name in a change summary.
+const maxSummaryItems = 10
+
+var errEditConflict = errors.New("the playlist changed while the edit awaited confirmation")
+
+type playlistIDArg struct {
+	PlaylistID string `json:"playlist_id" description:"Spotify playlist ID" jsonschema:"required"`
+}
+
+type urisArg struct {
+	URIs []string `json:"uris" description:"Spotify URIs of the tracks or episodes, e.g. spotify:track:4uLU6hMCjMI75M1A2tKUQC" jsonschema:"required"`
+}
+
+// dryRunArg is accepted by every tool that changes Spotify state.
+type dryRunArg struct {
+	DryRun bool `json:"dry_run,omitempty" description:"Return the Spotify API calls and the predicted playlist without changing anything" jsonschema:"default=false"`
+}
+
+type addPlaylistItemsArgs struct {
+	playlistIDArg
+	urisArg
+	Position *int `json:"position,omitempty" description:"Zero-based index to insert the items at (default: the end of the playlist)" jsonschema:"minimum=0"`
+	dryRunArg
+}
+
+type removePlaylistItemsArgs struct {
+	playlistIDArg
+	urisArg
+	dryRunArg
+}
+
+type replacePlaylistItemsArgs struct {
+	playlistIDArg
+	urisArg
+	dryRunArg
+}
+
+type reorderPlaylistItemsArgs struct {
+	playlistIDArg
+	RangeStart   int `json:"range_start" description:"Zero-based index of the first item to move" jsonschema:"required,minimum=0"`
+	RangeLength  int `json:"range_length,omitempty" description:"Number of consecutive items to move" jsonschema:"minimum=1,default=1"`
+	InsertBefore int `json:"insert_before" description:"Zero-based index, counted before the move, of the item to move the range in front of; the number of items moves it to the end" jsonschema:"required,minimum=0"`
+	dryRunArg
+}
+
+// PlaylistEditResult reports what a playlist edit did. Edits that need
+// confirmation are only applied once the user confirms them; dry runs
+// report the calls the edit would make and its predicted effect instead.
+type PlaylistEditResult struct {
+	PlaylistID string `json:"playlist_id"`
+	Status     string `json:"status" description:"applied, declined by the user, or dry_run when the change was described but not applied" jsonschema:"enum=applied|declined|dry_run"`
+	Summary    string `json:"summary"`
+	// SnapshotID is the playlist's version after the edit was applied
+	SnapshotID string                `json:"snapshot_id,omitempty"`
+	ChangeID   int64                 `json:"change_id,omitempty" description:"Journal entry of the applied edit; pass it to undo_change to revert the edit"`
+	Calls      []spotify.PlannedCall `json:"calls,omitempty" description:"Spotify API requests the edit would send, in order"`
+	Preview    *PlaylistPreview      `json:"preview,omitempty"`
+}
+
+// PlaylistPreview is the predicted effect of an edit on a playlist.
+type PlaylistPreview struct {
+	// SnapshotID is the version of the playlist the prediction starts from
+	SnapshotID  string               `json:"snapshot_id"`
+	ItemsBefore int                  `json:"items_before"`
+	Removed     []PlaylistItemChange `json:"removed" description:"Items removed, at their positions before the edit"`
+	Added       []PlaylistItemChange `json:"added" description:"Items added, at their positions after the edit"`
+	After       []string             `json:"after" description:"URIs of the playlist's items after the edit, in order"`
+}
+
+// PlaylistItemChange is an item added to or removed from a playlist.
+type PlaylistItemChange struct {
+	Position int    `json:"position"`
+	URI      string `json:"uri"`
+	Name     string `json:"name,omitempty"`
+	Artist   string `json:"artist,omitempty"`
+}
+
+// playlistEdit is a planned change to a playlist's items.
+type playlistEdit struct {
+	// tool is recorded in the journal along with inverse, which reverts
+	// the edit. Edits without an inverse can't be undone.
+	tool    string
+	inverse *Operation
+	// undoes is the journal entry the edit reverts, if any
+	undoes  int64
+	before  *spotify.PlaylistItems
+	after   []spotify.Track
+	removed []PlaylistItemChange
+	added   []PlaylistItemChange
+	summary string
+	// destructive edits need the user's confirmation
+	destructive bool
+	// apply makes the change, or records its calls under a dry run, and
+	// returns the new snapshot ID.
+	apply func(ctx context.Context) (string, error)
+}
+
+func (s *Server) handleGetPlaylistItems(ctx context.Context, args playlistIDArg) (*spotify.PlaylistItems, error) {
+	return s.spotifyClient.GetPlaylistItems(ctx, args.PlaylistID)
+}
+
+func (s *Server) handleAddPlaylistItems(ctx context.Context, args addPlaylistItemsArgs) (*PlaylistEditResult, error) {
+	if len(args.URIs) == 0 {
+		return nil, fmt.Errorf("%w: uris must not be empty", errInvalidArguments)
+	}
+	before, err := s.spotifyClient.GetPlaylistItems(ctx, args.PlaylistID)
+	if err != nil {
+		return nil, err
+	}
+
+	at := len(before.Items)
+	if args.Position != nil {
+		if *args.Position > len(before.Items) {
+			return nil, fmt.Errorf("%w: position must be at most %d, the number of items in the playlist", errInvalidArguments, len(before.Items))
+		}
+		at = *args.Position
+	}
+	added := itemsFor(args.URIs, before.Items)
+
+	edit := &playlistEdit{
+		tool:    "add_playlist_items",
+		before:  before,
+		after:   slices.Insert(slices.Clone(before.Items), at, added...),
+		added:   itemChanges(added, at),
+		summary: fmt.Sprintf("Add %d items to playlist %q at position %d of %d", len(added), before.Name, at, len(before.Items)),
+		apply: func(ctx context.Context) (string, error) {
+			return s.spotifyClient.AddPlaylistItems(ctx, args.PlaylistID, args.URIs, args.Position)
+		},
+	}
+	edit.inverse = &Operation{Kind: operationRemove, Items: edit.added}
+	return s.runEdit(ctx, edit, args.DryRun)
+}
+
+func (s *Server) handleRemovePlaylistItems(ctx context.Context, args removePlaylistItemsArgs) (*PlaylistEditResult, error) {
+	if len(args.URIs) == 0 {
+		return nil, fmt.Errorf("%w: uris must not be empty", errInvalidArguments)
+	}
+	before, err := s.spotifyClient.GetPlaylistItems(ctx, args.PlaylistID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every occurrence is removed
+	var matches []PlaylistItemChange
+	for i, item := range before.Items {
+		if item.URI != "" && slices.Contains(args.URIs, item.URI) {
+			matches = append(matches, itemChange(item, i))
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: none of the uris are in playlist %q", errInvalidArguments, before.Name)
+	}
+
+	edit := removeEdit(before, matches)
+	edit.tool = "remove_playlist_items"
+	edit.inverse = &Operation{Kind: operationInsert, Items: matches}
+	edit.apply = func(ctx context.Context) (string, error) {
+		return s.spotifyClient.RemovePlaylistItems(ctx, args.PlaylistID, args.URIs, before.SnapshotID)
+	}
+	return s.runEdit(ctx, edit, args.DryRun)
+}
+
+func (s *Server) handleReplacePlaylistItems(ctx context.Context, args replacePlaylistItemsArgs) (*PlaylistEditResult, error) {
+	before, err := s.spotifyClient.GetPlaylistItems(ctx, args.PlaylistID)
+	if err != nil {
+		return nil, err
+	}
+
+	after := itemsFor(args.URIs, before.Items)
+	edit := &playlistEdit{
+		tool:        "replace_playlist_items",
+		inverse:     restoreItems(before),
+		before:      before,
+		after:       after,
+		removed:     itemChanges(before.Items, 0),
+		added:       itemChanges(after, 0),
+		destructive: true,
+		apply: func(ctx context.Context) (string, error) {
+			return s.spotifyClient.ReplacePlaylistItems(ctx, args.PlaylistID, args.URIs)
+		},
+	}
+
+	var removed []spotify.Track
+	for _, item := range before.Items {
+		if !slices.Contains(args.URIs, item.URI) {
+			removed = append(removed, item)
+		}
+	}
+	edit.summary = fmt.Sprintf("Replace the %d items in playlist %q with %d items", len(before.Items), before.Name, len(args.URIs))
+	if len(removed) > 0 {
+		edit.summary += fmt.Sprintf(", removing %d:\n%s", len(removed), describeItems(removed))
+	}
+	return s.runEdit(ctx, edit, args.DryRun)
+}
+
+func (s *Server) handleReorderPlaylistItems(ctx context.Context, args reorderPlaylistItemsArgs) (*PlaylistEditResult, error) {
+	before, err := s.spotifyClient.GetPlaylistItems(ctx, args.PlaylistID)
+	if err != nil {
+		return nil, err
+	}
+
+	move := ReorderRange{RangeStart: args.RangeStart, RangeLength: args.RangeLength, InsertBefore: args.InsertBefore}
+	if err := validateMove(move, len(before.Items)); err != nil {
+		return nil, err
+	}
+	undo := move.inverse()
+	edit := reorderEdit(before, move)
+	edit.tool = "reorder_playlist_items"
+	edit.inverse = &Operation{Kind: operationReorder, Reorder: &undo}
+	edit.apply = func(ctx context.Context) (string, error) {
+		return s.spotifyClient.ReorderPlaylistItems(ctx, args.PlaylistID, move.RangeStart, move.RangeLength, move.InsertBefore, before.SnapshotID)
+	}
+	return s.runEdit(ctx, edit, args.DryRun)
+}
+
+func validateMove(move ReorderRange, items int) error {
+	if move.RangeStart+move.RangeLength > items {
+		return fmt.Errorf("%w: range_start plus range_length must be at most %d, the number of items in the playlist", errInvalidArguments, items)
+	}
+	if move.InsertBefore > items {
+		return fmt.Errorf("%w: insert_before must be at most %d, the number of items in the playlist", errInvalidArguments, items)
+	}
+	return nil
+}
+
+// reorderEdit plans a move without the call that makes it.
+func reorderEdit(before *spotify.PlaylistItems, move ReorderRange) *playlistEdit {
+	moved := before.Items[move.RangeStart : move.RangeStart+move.RangeLength]
+	to := move.inverse().RangeStart
+	return &playlistEdit{
+		before:  before,
+		after:   moveTracks(before.Items, move),
+		removed: itemChanges(moved, move.RangeStart),
+		added:   itemChanges(moved, to),
+		summary: fmt.Sprintf("Move %d items in playlist %q from position %d to %d:\n%s",
+			len(moved), before.Name, move.RangeStart, to, describeItems(moved)),
+	}
+}
+
+// moveTracks returns items with a range moved as Spotify would move it.
+func moveTracks(items []spotify.Track, move ReorderRange) []spotify.Track {
+	end := move.RangeStart + move.RangeLength
+	if move.InsertBefore >= move.RangeStart && move.InsertBefore <= end {
+		return slices.Clone(items)
+	}
+	moved := slices.Clone(items[move.RangeStart:end])
+	rest := slices.Delete(slices.Clone(items), move.RangeStart, end)
+	return slices.Insert(rest, move.inverse().RangeStart, moved...)
+}
+
+// removeEdit plans removing items at their positions, without the call
+// that removes them. It returns nil when the items aren't at those
+// positions.
+func removeEdit(before *spotify.PlaylistItems, items []PlaylistItemChange) *playlistEdit {
+	remove := make(map[int]bool, len(items))
+	for _, item := range items {
+		if item.Position >= len(before.Items) || before.Items[item.Position].URI != item.URI {
+			return nil
+		}
+		remove[item.Position] = true
+	}
+
+	edit := &playlistEdit{before: before, removed: items, destructive: true}
+	var removed []spotify.Track
+	for i, item := range before.Items {
+		if remove[i] {
+			removed = append(removed, item)
+		} else {
+			edit.after = append(edit.after, item)
+		}
+	}
+	edit.summary = fmt.Sprintf("Remove %d of the %d items in playlist %q:\n%s",
+		len(removed), len(before.Items), before.Name, describeItems(removed))
+	return edit
+}
+
+// insertEdit plans inserting items at their positions, without the calls
+// that insert them. It returns nil when a position is past the end of the
+// playlist.
+func insertEdit(before *spotify.PlaylistItems, items []PlaylistItemChange) *playlistEdit {
+	after := slices.Clone(before.Items)
+	inserted := make([]spotify.Track, len(items))
+	for i, item := range items {
+		if item.Position > len(after) {
+			return nil
+		}
+		inserted[i] = spotify.Track{URI: item.URI, Name: item.Name, Artist: item.Artist}
+		after = slices.Insert(after, item.Position, inserted[i])
+	}
+	return &playlistEdit{
+		before:  before,
+		after:   after,
+		added:   items,
+		summary: fmt.Sprintf("Put back %d items in playlist %q at their positions:\n%s", len(items), before.Name, describeItems(inserted)),
+	}
+}
+
+// positionRuns splits items, ascending by position, into runs at
+// consecutive positions, which one call can insert each.
+func positionRuns(items []PlaylistItemChange) [][]PlaylistItemChange {
+	var runs [][]PlaylistItemChange
+	for i, item := range items {
+		if i > 0 && item.Position == items[i-1].Position+1 {
+			runs[len(runs)-1] = append(runs[len(runs)-1], item)
+			continue
+		}
+		runs = append(runs, []PlaylistItemChange{item})
+	}
+	return runs
+}
+
+// restoreItems returns the operation that puts back a playlist's items.
+// Items Spotify no longer has can't be put back, and are left out.
+func restoreItems(items *spotify.PlaylistItems) *Operation {
+	op := &Operation{Kind: operationReplace, URIs: []string{}}
+	for _, item := range items.Items {
+		if item.URI != "" {
+			op.URIs = append(op.URIs, item.URI)
+		}
+	}
+	return op
+}
+
+// runEdit applies an edit, once the user confirms it if it is destructive,
+// and records it in the journal. Dry runs, and destructive edits the user
+// couldn't be asked about, are described instead.
+func (s *Server) runEdit(ctx context.Context, edit *playlistEdit, dryRun bool) (*PlaylistEditResult, error) {
+	playlistID := edit.before.PlaylistID
+	result := &PlaylistEditResult{PlaylistID: playlistID, Summary: edit.summary}
+
+	if !dryRun && edit.destructive {
+		outcome, err := s.confirmChange(ctx, edit.summary)
+		if err != nil {
+			return nil, err
+		}
+		switch outcome {
+		case declined:
+			s.logger.WithContext(ctx).Infof("Declined change to playlist %s", playlistID)
+			result.Status = editDeclined
+			return result, nil
+		case unconfirmed:
+			dryRun = true
+		default:
+			// The user may have taken a while; what they confirmed must
+			// still be what gets changed
+			current, err := s.spotifyClient.GetPlaylistItems(ctx, playlistID)
+			if err != nil {
+				return nil, err
+			}
+			if current.SnapshotID != edit.before.SnapshotID {
+				return nil, fmt.Errorf("%w: playlist %q is now at snapshot %s, not %s; review it and try again",
+					errEditConflict, edit.before.Name, current.SnapshotID, edit.before.SnapshotID)
+			}
+		}
+	}
+
+	if dryRun {
+		// The same client calls as the live edit, recorded rather than sent
+		planCtx, plan := spotify.WithDryRun(ctx)
+		if _, err := edit.apply(planCtx); err != nil {
+			return nil, err
+		}
+		result.Status = editDryRun
+		result.Calls = plan.Calls()
+		result.Preview = edit.preview()
+		return result, nil
+	}
+
+	snapshotID, err := edit.apply(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.WithContext(ctx).Infof("Applied change to playlist %s", playlistID)
+	result.Status = editApplied
+	result.SnapshotID = snapshotID
+
+	result.ChangeID, err = s.changeJournal().record(Change{
+		Time:           time.Now().UTC(),
+		Tool:           edit.tool,
+		PlaylistID:     playlistID,
+		PlaylistName:   edit.before.Name,
+		Summary:        edit.summary,
+		SnapshotBefore: edit.before.SnapshotID,
+		SnapshotAfter:  snapshotID,
+		Inverse:        edit.inverse,
+		Undoes:         edit.undoes,
+	})
+	if err != nil {
+		// The change is applied and still journaled in memory
+		s.logger.WithContext(ctx).Warnf("Failed to save change journal: %v", err)
+	}
+	return result, nil
+}
+
+func (e *playlistEdit) preview() *PlaylistPreview {
+	preview := &PlaylistPreview{
+		SnapshotID:  e.before.SnapshotID,
+		ItemsBefore: len(e.before.Items),
+		Removed:     e.removed,
+		Added:       e.added,
+		After:       make([]string, len(e.after)),
+	}
+	if preview.Removed == nil {
+		preview.Removed = []PlaylistItemChange{}
+	}
+	if preview.Added == nil {
+		preview.Added = []PlaylistItemChange{}
+	}
+	for i, item := range e.after {
+		preview.After[i] = item.URI
+	}
+	return preview
+}
+
+// itemsFor returns the items for uris, named when they are already among
+// known.
+func itemsFor(uris []string, known []spotify.Track) []spotify.Track {
+	items := make([]spotify.Track, len(uris))
+	for i, uri := range uris {
+		items[i] = spotify.Track{URI: uri}
+		if j := slices.IndexFunc(known, func(item spotify.Track) bool { return item.URI == uri }); j >= 0 {
+			items[i] = known[j]
+		}
+	}
+	return items
+}
+
+func itemChange(item spotify.Track, position int) PlaylistItemChange {
+	return PlaylistItemChange{Position: position, URI: item.URI, Name: item.Name, Artist: item.Artist}
+}
+
+// itemChanges describes consecutive items starting at position.
+func itemChanges(items []spotify.Track, position int) []PlaylistItemChange {
+	changes := make([]PlaylistItemChange, len(items))
+	for i, item := range items {
+		changes[i] = itemChange(item, position+i)
+	}
+	return changes
+}
+
+// describeItems lists items one per line, naming the first few.
+func describeItems(items []spotify.Track) string {
+	var b strings.Builder
+	for i, item := range items {
+		if i == maxSummaryItems {
+			fmt.Fprintf(&b, "- and %d more\n", len(items)-i)
+			break
+		}
+		switch {
+		case item.URI == "":
+			b.WriteString("- an item Spotify no longer has\n")
+			continue
+		case item.Name == "":
+			fmt.Fprintf(&b, "- %s\n", item.URI)
+			continue
+		}
+		fmt.Fprintf(&b, "- %s by %s (%s)\n", item.Name, item.Artist, item.URI)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,314 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+	"github.com/anuragkothare/spotify_mcp_server/internal/spotify/spotifytest"
+)
+
+const (
+	roadTripID = "3cEYpjA9oz9GiPac4AsH4n"
+	rainyDayID = "1XhVM7jWPrGLTiNiAy97Za"
+
+	trackA = "spotify:track:4u7EnebtmKWzUH433cf5Qv"
+	trackB = "spotify:track:4uLU6hMCjMI75M1A2tKUQC"
+	trackC = "spotify:track:1lCRw5FEZ1gPDNPzy1K4zW"
+)
+
+// playlistURIs returns a playlist's items as the fake has them now, with
+// "" for items Spotify no longer has.
+func playlistURIs(t *testing.T, fake *spotifytest.Fake, playlistID string) []string {
+	t.Helper()
+	items, err := fake.GetPlaylistItems(context.Background(), playlistID)
+	if err != nil {
+		t.Fatalf("GetPlaylistItems: %v", err)
+	}
+	uris := make([]string, len(items.Items))
+	for i, item := range items.Items {
+		uris[i] = item.URI
+	}
+	return uris
+}
+
+func expectItems(t *testing.T, fake *spotifytest.Fake, playlistID string, want ...string) {
+	t.Helper()
+	if got := playlistURIs(t, fake, playlistID); !slices.Equal(got, want) {
+		t.Errorf("playlist %s has %v, want %v", playlistID, got, want)
+	}
+}
+
+func editPlaylist(t *testing.T, s *Server, ctx context.Context, tool string, args map[string]interface{}) PlaylistEditResult {
+	t.Helper()
+	return toolOutput[PlaylistEditResult](t, callTool(t, s, ctx, tool, args))
+}
+
+func TestAddPlaylistItems(t *testing.T) {
+	s, fake := newTestServer(t)
+
+	result := editPlaylist(t, s, context.Background(), "add_playlist_items", map[string]interface{}{
+		"playlist_id": roadTripID,
+		"uris":        []string{trackC},
+		"position":    1,
+	})
+	if result.Status != editApplied || result.ChangeID == 0 {
+		t.Errorf("got %+v, want an applied, journaled edit", result)
+	}
+	expectItems(t, fake, roadTripID, trackA, trackC, trackB)
+
+	err := expectError(t, callTool(t, s, context.Background(), "add_playlist_items", map[string]interface{}{
+		"playlist_id": roadTripID,
+		"uris":        []string{trackC},
+		"position":    4,
+	}), ErrorCodeInvalidParams)
+	if !strings.Contains(err.Message, "position must be at most 3") {
+		t.Errorf("got %q", err.Message)
+	}
+}
+
+func TestRemovePlaylistItemsDryRun(t *testing.T) {
+	s, fake := newTestServer(t)
+
+	result := editPlaylist(t, s, context.Background(), "remove_playlist_items", map[string]interface{}{
+		"playlist_id": roadTripID,
+		"uris":        []string{trackA},
+		"dry_run":     true,
+	})
+	if result.Status != editDryRun || result.ChangeID != 0 {
+		t.Errorf("got %+v, want an unjournaled dry run", result)
+	}
+	if len(result.Calls) != 1 || result.Calls[0].Method != "DELETE" {
+		t.Errorf("got calls %+v, want one DELETE", result.Calls)
+	}
+	if result.Preview == nil || !slices.Equal(result.Preview.After, []string{trackB}) ||
+		len(result.Preview.Removed) != 1 || result.Preview.Removed[0].Position != 0 {
+		t.Errorf("got preview %+v", result.Preview)
+	}
+	expectItems(t, fake, roadTripID, trackA, trackB)
+}
+
+func TestRemovePlaylistItemsUnavailable(t *testing.T) {
+	s, fake := newTestServer(t)
+
+	// The first item is one Spotify no longer has; it keeps its place
+	items := toolOutput[struct {
+		Items []json.RawMessage `json:"items"`
+	}](t, callTool(t, s, context.Background(), "get_playlist_items", map[string]interface{}{"playlist_id": rainyDayID}))
+	if len(items.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items.Items))
+	}
+
+	result := editPlaylist(t, s, context.Background(), "remove_playlist_items", map[string]interface{}{
+		"playlist_id": rainyDayID,
+		"uris":        []string{trackC},
+	})
+	if result.Status != editApplied {
+		t.Fatalf("got %+v", result)
+	}
+	expectItems(t, fake, rainyDayID, "")
+
+	editPlaylist(t, s, context.Background(), "undo_change", map[string]interface{}{"change_id": result.ChangeID})
+	expectItems(t, fake, rainyDayID, "", trackC)
+}
+
+func TestConfirmationFallback(t *testing.T) {
+	tests := []struct {
+		fallback string
+		status   string
+		err      bool
+	}{
+		{fallbackDryRun, editDryRun, false},
+		{fallbackAllow, editApplied, false},
+		{fallbackReject, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.fallback, func(t *testing.T) {
+			s, fake := newTestServer(t)
+			err := s.ConfigureTools(config.ToolsConfig{
+				Groups:              toolGroups,
+				ConfirmDestructive:  true,
+				ElicitationFallback: test.fallback,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A session that didn't declare elicitation can't be asked
+			ctx := WithSession(context.Background(), s.NewSession(context.Background()))
+			response := callTool(t, s, ctx, "remove_playlist_items", map[string]interface{}{
+				"playlist_id": roadTripID,
+				"uris":        []string{trackA},
+			})
+			if test.err {
+				err := expectError(t, response, ErrorCodeInternalError)
+				if !strings.Contains(err.Message, errConfirmationUnavailable.Error()) {
+					t.Errorf("got %q", err.Message)
+				}
+				expectItems(t, fake, roadTripID, trackA, trackB)
+				return
+			}
+			if result := toolOutput[PlaylistEditResult](t, response); result.Status != test.status {
+				t.Errorf("got status %q, want %q", result.Status, test.status)
+			}
+		})
+	}
+}
+
+// elicitingClient is a session that declared elicitation and has a stream
+// open to receive the server's requests on.
+type elicitingClient struct {
+	t      *testing.T
+	s      *Server
+	ctx    context.Context
+	outbox <-chan []byte
+}
+
+func newElicitingClient(t *testing.T, s *Server) *elicitingClient {
+	t.Helper()
+	session := s.NewSession(context.Background())
+	ctx := WithSession(context.Background(), session)
+	decodeResult(t, s.HandleRequest(ctx, newRequest(t, 0, "initialize", map[string]interface{}{
+		"protocolVersion": "2025-06-18",
+		"capabilities":    map[string]interface{}{"elicitation": map[string]interface{}{}},
+		"clientInfo":      map[string]interface{}{"name": "test", "version": "1"},
+	})), &InitializeResponse{})
+
+	outbox, release, ok := session.Stream()
+	if !ok {
+		t.Fatal("stream already claimed")
+	}
+	t.Cleanup(release)
+	return &elicitingClient{t: t, s: s, ctx: ctx, outbox: outbox}
+}
+
+// confirmation waits for the server to ask the user to confirm a change.
+func (c *elicitingClient) confirmation() *MCPServerRequest {
+	c.t.Helper()
+	for {
+		select {
+		case data := <-c.outbox:
+			var req MCPServerRequest
+			if err := json.Unmarshal(data, &req); err != nil {
+				c.t.Fatalf("unmarshal %s: %v", data, err)
+			}
+			if req.Method == "elicitation/create" {
+				return &req
+			}
+		case <-time.After(5 * time.Second):
+			c.t.Fatal("no confirmation requested")
+		}
+	}
+}
+
+// answer replies to a server request the way the client would POST it.
+func (c *elicitingClient) answer(req *MCPServerRequest, result ElicitResult) {
+	c.t.Helper()
+	data, err := json.Marshal(result)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	var reply MCPRequest
+	if err := json.Unmarshal([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%q,"result":%s}`, req.ID, data)), &reply); err != nil {
+		c.t.Fatal(err)
+	}
+	if response := c.s.HandleRequest(c.ctx, &reply); response != nil {
+		c.t.Errorf("reply got response %+v", response)
+	}
+}
+
+// callAsync calls a tool and returns a channel the response arrives on.
+func (c *elicitingClient) callAsync(name string, args interface{}) <-chan *MCPResponse {
+	responses := make(chan *MCPResponse, 1)
+	req := newRequest(c.t, 1, "tools/call", map[string]interface{}{"name": name, "arguments": args})
+	go func() {
+		responses <- c.s.HandleRequest(c.ctx, req)
+	}()
+	return responses
+}
+
+func newConfirmingServer(t *testing.T) (*Server, *spotifytest.Fake) {
+	t.Helper()
+	s, fake := newTestServer(t)
+	if err := s.ConfigureTools(config.ToolsConfig{Groups: ToolGroups(), ConfirmDestructive: true}); err != nil {
+		t.Fatal(err)
+	}
+	return s, fake
+}
+
+func TestRemovePlaylistItemsConfirmed(t *testing.T) {
+	for _, test := range []struct {
+		answer ElicitResult
+		status string
+		after  []string
+	}{
+		{ElicitResult{Action: "accept", Content: map[string]interface{}{"confirm": true}}, editApplied, []string{trackB}},
+		{ElicitResult{Action: "accept", Content: map[string]interface{}{"confirm": false}}, editDeclined, []string{trackA, trackB}},
+		{ElicitResult{Action: "decline"}, editDeclined, []string{trackA, trackB}},
+	} {
+		s, fake := newConfirmingServer(t)
+		client := newElicitingClient(t, s)
+
+		responses := client.callAsync("remove_playlist_items", map[string]interface{}{
+			"playlist_id": roadTripID,
+			"uris":        []string{trackA},
+		})
+		client.answer(client.confirmation(), test.answer)
+
+		if result := toolOutput[PlaylistEditResult](t, <-responses); result.Status != test.status {
+			t.Errorf("answer %+v: got status %q, want %q", test.answer, result.Status, test.status)
+		}
+		expectItems(t, fake, roadTripID, test.after...)
+	}
+}
+
+func TestRemovePlaylistItemsChangedWhileConfirming(t *testing.T) {
+	s, fake := newConfirmingServer(t)
+	client := newElicitingClient(t, s)
+
+	responses := client.callAsync("remove_playlist_items", map[string]interface{}{
+		"playlist_id": roadTripID,
+		"uris":        []string{trackA},
+	})
+	confirmation := client.confirmation()
+
+	// Someone else edits the playlist before the user answers
+	if _, err := fake.AddPlaylistItems(context.Background(), roadTripID, []string{trackC}, nil); err != nil {
+		t.Fatal(err)
+	}
+	client.answer(confirmation, ElicitResult{Action: "accept", Content: map[string]interface{}{"confirm": true}})
+
+	err := expectError(t, <-responses, ErrorCodeInternalError)
+	if !strings.Contains(err.Message, errEditConflict.Error()) {
+		t.Errorf("got %q, want an edit conflict", err.Message)
+	}
+	expectItems(t, fake, roadTripID, trackA, trackB, trackC)
+}
+
+func TestConfirmationSessionClosed(t *testing.T) {
+	s, fake := newConfirmingServer(t)
+	client := newElicitingClient(t, s)
+
+	responses := client.callAsync("replace_playlist_items", map[string]interface{}{
+		"playlist_id": roadTripID,
+		"uris":        []string{},
+	})
+	client.confirmation()
+	s.CloseSession(SessionFromContext(client.ctx).ID)
+
+	select {
+	case response := <-responses:
+		// The call ends without applying anything
+		if response != nil && response.Error == nil {
+			t.Errorf("got result %+v", response.Result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("call didn't return")
+	}
+	expectItems(t, fake, roadTripID, trackA, trackB)
+}
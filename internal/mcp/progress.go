@@ -0,0 +1,88 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// ProgressNotification represents the params of notifications/progress
+type ProgressNotification struct {
+	ProgressToken interface{} `json:"progressToken"`
+	Progress      float64     `json:"progress"`
+	Total         float64     `json:"total,omitempty"`
+	Message       string      `json:"message,omitempty"`
+}
+
+// requestMeta is the _meta object a client may attach to request params.
+type requestMeta struct {
+	Meta struct {
+		ProgressToken interface{} `json:"progressToken"`
+	} `json:"_meta"`
+}
+
+// progressToken returns the progress token of a request, or nil when the
+// client didn't ask for progress.
+func progressToken(params json.RawMessage) interface{} {
+	var meta requestMeta
+	if len(params) == 0 || json.Unmarshal(params, &meta) != nil {
+		return nil
+	}
+	switch token := meta.Meta.ProgressToken.(type) {
+	case string, float64:
+		return token
+	}
+	return nil
+}
+
+// Progress reports progress of a long-running tool call. A nil *Progress
+// is valid and reports nothing, so handlers can call it unconditionally.
+type Progress struct {
+	server *Server
+	ctx    context.Context
+	token  interface{}
+
+	mu   sync.Mutex
+	last float64
+}
+
+type progressContextKey struct{}
+
+func withProgress(ctx context.Context, p *Progress) context.Context {
+	return context.WithValue(ctx, progressContextKey{}, p)
+}
+
+// ProgressFromContext returns the reporter for the current tool call, or
+// nil when the client didn't send a progress token.
+func ProgressFromContext(ctx context.Context) *Progress {
+	p, _ := ctx.Value(progressContextKey{}).(*Progress)
+	return p
+}
+
+// Report sends notifications/progress. Total may be zero when unknown.
+// Progress must increase, so reports that don't are ignored.
+func (p *Progress) Report(current, total int, message string) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	if float64(current) <= p.last {
+		p.mu.Unlock()
+		return
+	}
+	p.last = float64(current)
+	p.mu.Unlock()
+
+	p.server.notify(p.ctx, "notifications/progress", ProgressNotification{
+		ProgressToken: p.token,
+		Progress:      float64(current),
+		Total:         float64(total),
+		Message:       message,
+	})
+}
+
+// WantsProgress reports whether the client asked for progress on req.
+func WantsProgress(req *MCPRequest) bool {
+	return progressToken(req.Params) != nil
+}
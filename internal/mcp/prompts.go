@@ -0,0 +1,383 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/spotify"
+	"github.com/spf13/viper"
+)
+
+// Prompt is a parameterized prompt template. Messages and resource URIs
+// may reference arguments as {name}; each resource is read and embedded
+// in the prompt after the messages.
+type Prompt struct {
+	Name        string               `mapstructure:"name"`
+	Title       string               `mapstructure:"title"`
+	Description string               `mapstructure:"description"`
+	Arguments   []PromptArgumentSpec `mapstructure:"arguments"`
+	Messages    []PromptMessageSpec  `mapstructure:"messages"`
+	Resources   []string             `mapstructure:"resources"`
+}
+
+// PromptArgumentSpec declares a prompt argument. Resolve, when set to
+// "artist" or "track", lets callers pass a name: it is looked up by search
+// and resource URIs receive the entity's ID, while messages keep the name.
+// Complete picks the completion/complete source ("artist", "track",
+// "playlist", "device" or "category") and defaults to Resolve.
+type PromptArgumentSpec struct {
+	Name        string `mapstructure:"name"`
+	Description string `mapstructure:"description"`
+	Required    bool   `mapstructure:"required"`
+	Resolve     string `mapstructure:"resolve"`
+	Complete    string `mapstructure:"complete"`
+}
+
+// PromptMessageSpec is a templated message; Role defaults to "user".
+type PromptMessageSpec struct {
+	Role string `mapstructure:"role"`
+	Text string `mapstructure:"text"`
+}
+
+var (
+	placeholderPattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	spotifyIDPattern   = regexp.MustCompile(`^[0-9A-Za-z]{22}$`)
+)
+
+var errInvalidPromptArgument = errors.New("invalid prompt argument")
+
+var builtinPrompts = []*Prompt{
+	{
+		Name:        "build_playlist",
+		Title:       "Build a playlist",
+		Description: "Build a playlist for a mood and length",
+		Arguments: []PromptArgumentSpec{
+			{Name: "mood", Description: "Mood or occasion, e.g. focus or summer party", Required: true, Complete: completeCategory},
+			{Name: "minutes", Description: "Approximate length in minutes", Required: true},
+		},
+		Messages: []PromptMessageSpec{{
+			Text: "Build a playlist for a {mood} mood lasting about {minutes} minutes. " +
+				"Use the browse categories below and search_tracks to find candidates, " +
+				"skip tracks that are not playable in my market, and vary the artists. " +
+				"List each track with its artist and Spotify URI.",
+		}},
+		Resources: []string{categoriesURI},
+	},
+	{
+		Name:        "summarize_listening",
+		Title:       "Summarize my listening",
+		Description: "Summarize what the authorized user has been listening to this month, from their top artists and tracks",
+		Messages: []PromptMessageSpec{{
+			Text: "Summarize what I've been listening to on Spotify this month: the " +
+				"artists, genres and tracks that come up most in my top items from " +
+				"the last four weeks below, and anything that stands out. Compare " +
+				"them with the playlists I keep, using the playlist tools.",
+		}},
+		Resources: []string{currentUserURI, topItemsURI + "?time_range=" + spotify.TimeRangeShort},
+	},
+	{
+		Name:        "explain_discography",
+		Title:       "Explain an artist's discography",
+		Description: "Walk through an artist's discography",
+		Arguments: []PromptArgumentSpec{
+			{Name: "artist", Description: "Artist name or Spotify ID", Required: true, Resolve: completeArtist},
+		},
+		Messages: []PromptMessageSpec{{
+			Text: "Explain the discography of {artist}: the main eras and albums, how the " +
+				"sound changed over time, and where a new listener should start.",
+		}},
+		Resources: []string{"spotify://artist/{artist}"},
+	},
+}
+
+func (s *Server) registerPrompts() {
+	for _, prompt := range builtinPrompts {
+		if err := s.RegisterPrompt(prompt); err != nil {
+			panic(fmt.Sprintf("builtin prompt %s: %v", prompt.Name, err))
+		}
+	}
+}
+
+// RegisterPrompt adds a prompt, replacing any prompt with the same name.
+func (s *Server) RegisterPrompt(prompt *Prompt) error {
+	if err := prompt.validate(); err != nil {
+		return err
+	}
+	s.prompts[prompt.Name] = prompt
+	return nil
+}
+
+// LoadPrompts registers the prompts listed under "prompts" in a YAML file.
+// A missing file is not an error.
+func (s *Server) LoadPrompts(path string) error {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read prompts file: %w", err)
+	}
+
+	var prompts []*Prompt
+	if err := v.UnmarshalKey("prompts", &prompts); err != nil {
+		return fmt.Errorf("failed to parse prompts file: %w", err)
+	}
+	for _, prompt := range prompts {
+		if err := s.RegisterPrompt(prompt); err != nil {
+			return fmt.Errorf("%s: prompt %q: %w", path, prompt.Name, err)
+		}
+	}
+
+	s.logger.Infof("Loaded %d prompts from %s", len(prompts), path)
+	return nil
+}
+
+func (p *Prompt) validate() error {
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(p.Messages) == 0 {
+		return errors.New("at least one message is required")
+	}
+
+	declared := make(map[string]bool, len(p.Arguments))
+	for _, arg := range p.Arguments {
+		if arg.Name == "" {
+			return errors.New("argument name is required")
+		}
+		switch arg.Resolve {
+		case "", completeArtist, completeTrack:
+		default:
+			return fmt.Errorf("argument %s: unknown resolve %q", arg.Name, arg.Resolve)
+		}
+		switch arg.Complete {
+		case "", completeArtist, completeTrack, completePlaylist, completeDevice, completeCategory:
+		default:
+			return fmt.Errorf("argument %s: unknown complete %q", arg.Name, arg.Complete)
+		}
+		declared[arg.Name] = true
+	}
+
+	templates := make([]string, 0, len(p.Messages)+len(p.Resources))
+	for _, message := range p.Messages {
+		switch message.Role {
+		case "", "user", "assistant":
+		default:
+			return fmt.Errorf("unknown message role %q", message.Role)
+		}
+		templates = append(templates, message.Text)
+	}
+	for _, uri := range p.Resources {
+		if !strings.HasPrefix(uri, resourceScheme+"://") {
+			return fmt.Errorf("resource %s is not a %s:// URI", uri, resourceScheme)
+		}
+		templates = append(templates, uri)
+	}
+	for _, text := range templates {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+			if !declared[match[1]] {
+				return fmt.Errorf("placeholder {%s} is not a declared argument", match[1])
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Prompt) info() PromptInfo {
+	args := make([]PromptArgument, len(p.Arguments))
+	for i, arg := range p.Arguments {
+		args[i] = PromptArgument{
+			Name:        arg.Name,
+			Description: arg.Description,
+			Required:    arg.Required,
+		}
+	}
+	return PromptInfo{
+		Name:        p.Name,
+		Title:       p.Title,
+		Description: p.Description,
+		Arguments:   args,
+	}
+}
+
+func (s *Server) handleListPrompts(ctx context.Context, req *MCPRequest) *MCPResponse {
+	prompts := make([]PromptInfo, 0, len(s.prompts))
+	for _, prompt := range s.prompts {
+		if promptAllowed(ctx, prompt) {
+			prompts = append(prompts, prompt.info())
+		}
+	}
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+
+	return &MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  ListPromptsResponse{Prompts: prompts},
+	}
+}
+
+func (s *Server) handleGetPrompt(ctx context.Context, req *MCPRequest) *MCPResponse {
+	var params GetPromptRequest
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.Name == "" {
+		return &MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &MCPError{
+				Code:    ErrorCodeInvalidParams,
+				Message: "Invalid parameters",
+			},
+		}
+	}
+
+	prompt, ok := s.prompts[params.Name]
+	if !ok || !promptAllowed(ctx, prompt) {
+		return &MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &MCPError{
+				Code:    ErrorCodeInvalidParams,
+				Message: fmt.Sprintf("Unknown prompt: %s", params.Name),
+			},
+		}
+	}
+
+	result, err := s.renderPrompt(ctx, prompt, params.Arguments)
+	if err != nil {
+		code := resourceErrorCode(err)
+		if errors.Is(err, errInvalidPromptArgument) {
+			code = ErrorCodeInvalidParams
+		}
+		return &MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &MCPError{
+				Code:    code,
+				Message: err.Error(),
+			},
+		}
+	}
+
+	return &MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  result,
+	}
+}
+
+// renderPrompt fills in the arguments and embeds the prompt's resources.
+func (s *Server) renderPrompt(ctx context.Context, prompt *Prompt, args map[string]string) (*GetPromptResponse, error) {
+	text := make(map[string]string, len(prompt.Arguments))
+	ids := make(map[string]string, len(prompt.Arguments))
+	for _, arg := range prompt.Arguments {
+		value := strings.TrimSpace(args[arg.Name])
+		if value == "" && arg.Required {
+			return nil, fmt.Errorf("%w: %s is required", errInvalidPromptArgument, arg.Name)
+		}
+		text[arg.Name] = value
+		ids[arg.Name] = value
+
+		if value != "" && arg.Resolve != "" {
+			id, err := s.resolveEntity(ctx, arg.Resolve, value)
+			if err != nil {
+				return nil, err
+			}
+			ids[arg.Name] = id
+		}
+	}
+
+	result := &GetPromptResponse{
+		Description: prompt.Description,
+		Messages:    make([]PromptMessage, 0, len(prompt.Messages)+len(prompt.Resources)),
+	}
+	for _, message := range prompt.Messages {
+		role := message.Role
+		if role == "" {
+			role = "user"
+		}
+		result.Messages = append(result.Messages, PromptMessage{
+			Role:    role,
+			Content: NewTextContent(fillPlaceholders(message.Text, text, false)),
+		})
+	}
+
+	for _, template := range prompt.Resources {
+		uri := fillPlaceholders(template, ids, true)
+		data, err := s.readResource(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+		body, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		result.Messages = append(result.Messages, PromptMessage{
+			Role: "user",
+			Content: Content{
+				Type: "resource",
+				Resource: &ResourceContent{
+					URI:      uri,
+					MimeType: "application/json",
+					Text:     string(body),
+				},
+			},
+		})
+	}
+
+	return result, nil
+}
+
+func fillPlaceholders(template string, values map[string]string, escape bool) string {
+	return placeholderPattern.ReplaceAllStringFunc(template, func(match string) string {
+		value := values[match[1:len(match)-1]]
+		if escape {
+			return url.PathEscape(value)
+		}
+		return value
+	})
+}
+
+// resolveEntity turns a Spotify ID, URI, open.spotify.com link or name into
+// an ID, searching by name as a last resort.
+func (s *Server) resolveEntity(ctx context.Context, kind, value string) (string, error) {
+	if id, ok := parseSpotifyID(kind, value); ok {
+		return id, nil
+	}
+
+	switch kind {
+	case completeArtist:
+		result, err := s.spotifyClient.SearchArtists(ctx, value, 1, "")
+		if err != nil {
+			return "", err
+		}
+		if len(result.Artists) > 0 {
+			return result.Artists[0].ID, nil
+		}
+	case completeTrack:
+		result, err := s.spotifyClient.SearchTracks(ctx, value, 1, "")
+		if err != nil {
+			return "", err
+		}
+		if len(result.Tracks) > 0 {
+			return result.Tracks[0].ID, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no %s found matching %q", errInvalidPromptArgument, kind, value)
+}
+
+func parseSpotifyID(kind, value string) (string, bool) {
+	if rest, ok := strings.CutPrefix(value, "spotify:"+kind+":"); ok {
+		value = rest
+	} else if u, err := url.Parse(value); err == nil && u.Host == "open.spotify.com" {
+		value = strings.TrimPrefix(u.Path, "/"+kind+"/")
+	}
+	return value, spotifyIDPattern.MatchString(value)
+}
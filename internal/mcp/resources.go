@@ -0,0 +1,262 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/spotify"
+	zspotify "github.com/zmb3/spotify/v2"
+)
+
+const (
+	resourceScheme = "spotify"
+	categoriesURI  = "spotify://browse/categories"
+	currentUserURI = "spotify://me"
+	topItemsURI    = "spotify://me/top"
+)
+
+var errResourceNotFound = errors.New("resource not found")
+
+// resourceTemplates are the spotify:// URIs that take an ID. Each accepts
+// an optional ?market= query, except artist.
+var resourceTemplates = []*ResourceTemplate{
+	{
+		URITemplate: "spotify://track/{id}",
+		Name:        "Track",
+		Description: "A Spotify track by ID",
+		MimeType:    "application/json",
+	},
+	{
+		URITemplate: "spotify://artist/{id}",
+		Name:        "Artist",
+		Description: "A Spotify artist by ID, with genres and follower count",
+		MimeType:    "application/json",
+	},
+	{
+		URITemplate: "spotify://album/{id}",
+		Name:        "Album",
+		Description: "A Spotify album by ID, with its tracks",
+		MimeType:    "application/json",
+	},
+	{
+		URITemplate: "spotify://playlist/{id}",
+		Name:        "Playlist",
+		Description: "A Spotify playlist by ID, with the first page of its tracks",
+		MimeType:    "application/json",
+	},
+	{
+		URITemplate: "spotify://user/{id}",
+		Name:        "User profile",
+		Description: "A Spotify user's public profile",
+		MimeType:    "application/json",
+	},
+	{
+		URITemplate: "spotify://browse/categories/{id}",
+		Name:        "Category playlists",
+		Description: "Playlists in a browse category",
+		MimeType:    "application/json",
+	},
+}
+
+func (s *Server) handleListResourceTemplates(ctx context.Context, req *MCPRequest) *MCPResponse {
+	templates := make([]*ResourceTemplate, 0, len(resourceTemplates))
+	for _, template := range resourceTemplates {
+		if resourceAllowed(ctx, template.URITemplate) {
+			templates = append(templates, template)
+		}
+	}
+	return &MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  ListResourceTemplatesResponse{ResourceTemplates: templates},
+	}
+}
+
+// handleListResources lists the fixed resources. It doesn't call Spotify,
+// so listing works while Spotify is down; individual categories and
+// entities are reached through resourceTemplates.
+func (s *Server) handleListResources(ctx context.Context, req *MCPRequest) *MCPResponse {
+	var params ListResourcesRequest
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return &MCPResponse{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error: &MCPError{
+					Code:    ErrorCodeInvalidParams,
+					Message: "Invalid parameters",
+				},
+			}
+		}
+	}
+
+	resources := []*Resource{}
+	if s.spotifyClient.UserAuthorized() && resourceAllowed(ctx, currentUserURI) {
+		resources = append(resources, &Resource{
+			URI:         currentUserURI,
+			Name:        "Current user",
+			Description: "Profile of the authorized Spotify user",
+			MimeType:    "application/json",
+		}, &Resource{
+			URI:         topItemsURI,
+			Name:        "Top artists and tracks",
+			Description: "The authorized user's most played artists and tracks over about the last four weeks; ?time_range=medium_term or long_term covers six months or a year",
+			MimeType:    "application/json",
+		})
+	}
+	if resourceAllowed(ctx, categoriesURI) {
+		resources = append(resources, &Resource{
+			URI:         categoriesURI,
+			Name:        "Browse categories",
+			Description: "Spotify browse categories; read spotify://browse/categories/{id} for a category's playlists",
+			MimeType:    "application/json",
+		})
+	}
+
+	return &MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  ListResourcesResponse{Resources: resources},
+	}
+}
+
+func (s *Server) handleReadResource(ctx context.Context, req *MCPRequest) *MCPResponse {
+	var params ReadResourceRequest
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+		return &MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &MCPError{
+				Code:    ErrorCodeInvalidParams,
+				Message: "Invalid parameters",
+			},
+		}
+	}
+
+	ctx, staleness := spotify.WithStalenessReport(ctx)
+
+	data, err := s.readResource(ctx, params.URI)
+	if err != nil {
+		return &MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &MCPError{
+				Code:    resourceErrorCode(err),
+				Message: err.Error(),
+			},
+		}
+	}
+
+	text, err := json.Marshal(data)
+	if err != nil {
+		return &MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &MCPError{
+				Code:    ErrorCodeInternalError,
+				Message: err.Error(),
+			},
+		}
+	}
+
+	result := ReadResourceResponse{
+		Contents: []ResourceContent{
+			{
+				URI:      params.URI,
+				MimeType: "application/json",
+				Text:     string(text),
+			},
+		},
+	}
+	if stale, cachedAt := staleness.Stale(); stale {
+		result.Meta = staleMeta(cachedAt)
+	}
+
+	return &MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  result,
+	}
+}
+
+// resourceErrorCode distinguishes unknown resources, including IDs Spotify
+// doesn't recognise, from resources that failed to load.
+func resourceErrorCode(err error) int {
+	var spotifyErr zspotify.Error
+	if errors.Is(err, errResourceNotFound) ||
+		errors.As(err, &spotifyErr) && (spotifyErr.Status == http.StatusNotFound || spotifyErr.Status == http.StatusBadRequest) {
+		return ErrorCodeResourceNotFound
+	}
+	return ErrorCodeResourceUnavailable
+}
+
+// readResource resolves a spotify:// URI. Paging and locale are passed as
+// query parameters, e.g. spotify://browse/categories/party?offset=20.
+func (s *Server) readResource(ctx context.Context, uri string) (interface{}, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != resourceScheme {
+		return nil, fmt.Errorf("%w: %s", errResourceNotFound, uri)
+	}
+	if !resourceAllowed(ctx, uri) {
+		// Reported like unknown resources, as with disabled tools
+		return nil, fmt.Errorf("%w: %s", errResourceNotFound, uri)
+	}
+
+	query := u.Query()
+	opts := spotify.BrowseOptions{
+		Locale:  query.Get("locale"),
+		Country: query.Get("country"),
+		Market:  query.Get("market"),
+		Limit:   20,
+	}
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > 0 {
+		opts.Limit = limit
+	}
+	if offset, err := strconv.Atoi(query.Get("offset")); err == nil && offset > 0 {
+		opts.Offset = offset
+	}
+
+	segments := strings.Split(strings.Trim(u.Host+u.Path, "/"), "/")
+	if len(segments) == 2 && segments[1] != "" {
+		id := segments[1]
+		switch segments[0] {
+		case "track":
+			return s.spotifyClient.GetTrack(ctx, id, opts.Market)
+		case "artist":
+			return s.spotifyClient.GetArtist(ctx, id)
+		case "album":
+			return s.spotifyClient.GetAlbum(ctx, id, opts.Market)
+		case "playlist":
+			return s.spotifyClient.GetPlaylist(ctx, id, opts.Market)
+		case "user":
+			return s.spotifyClient.GetUserProfile(ctx, id)
+		}
+	}
+
+	switch {
+	case len(segments) == 1 && segments[0] == "me":
+		return s.spotifyClient.GetCurrentUser(ctx)
+	case len(segments) == 2 && segments[0] == "me" && segments[1] == "top":
+		timeRange := query.Get("time_range")
+		if timeRange == "" {
+			timeRange = spotify.TimeRangeShort
+		}
+		if !slices.Contains(spotify.TimeRanges, timeRange) {
+			return nil, fmt.Errorf("%w: %s: time_range must be one of %v", errResourceNotFound, uri, spotify.TimeRanges)
+		}
+		return s.spotifyClient.GetTopItems(ctx, timeRange, opts.Limit)
+	case len(segments) == 2 && segments[0] == "browse" && segments[1] == "categories":
+		return s.spotifyClient.GetCategories(ctx, opts)
+	case len(segments) == 3 && segments[0] == "browse" && segments[1] == "categories" && segments[2] != "":
+		return s.spotifyClient.GetCategoryPlaylists(ctx, segments[2], opts)
+	}
+
+	return nil, fmt.Errorf("%w: %s", errResourceNotFound, uri)
+}
@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/spotify"
+)
+
+func TestReadTopItems(t *testing.T) {
+	s, _ := newTestServer(t)
+	read := func(uri string) *MCPResponse {
+		return s.HandleRequest(context.Background(), newRequest(t, 1, "resources/read", map[string]interface{}{"uri": uri}))
+	}
+
+	var result ReadResourceResponse
+	decodeResult(t, read(topItemsURI+"?limit=1"), &result)
+	var top spotify.TopItems
+	if len(result.Contents) != 1 {
+		t.Fatalf("got %d contents, want 1", len(result.Contents))
+	}
+	if err := json.Unmarshal([]byte(result.Contents[0].Text), &top); err != nil {
+		t.Fatal(err)
+	}
+	if top.TimeRange != spotify.TimeRangeShort || len(top.Artists) != 1 || len(top.Tracks) != 1 {
+		t.Errorf("got %+v, want one short_term artist and track", top)
+	}
+
+	expectError(t, read(topItemsURI+"?time_range=all_time"), ErrorCodeResourceNotFound)
+}
+
+func TestSummarizeListeningEmbedsTopItems(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	var prompt GetPromptResponse
+	decodeResult(t, s.HandleRequest(context.Background(), newRequest(t, 1, "prompts/get", map[string]interface{}{
+		"name": "summarize_listening",
+	})), &prompt)
+	var uris []string
+	for _, message := range prompt.Messages {
+		if message.Content.Resource != nil {
+			uris = append(uris, message.Content.Resource.URI)
+		}
+	}
+	want := topItemsURI + "?time_range=" + spotify.TimeRangeShort
+	if len(uris) != 2 || uris[1] != want {
+		t.Errorf("got embedded resources %v, want the profile and %s", uris, want)
+	}
+}
+
+func TestListResourcesWithoutSpotify(t *testing.T) {
+	s, fake := newTestServer(t)
+	fake.Err = fmt.Errorf("%w: 5 consecutive failures", spotify.ErrUnavailable)
+
+	var list ListResourcesResponse
+	decodeResult(t, s.HandleRequest(context.Background(), newRequest(t, 1, "resources/list", nil)), &list)
+	var uris []string
+	for _, resource := range list.Resources {
+		uris = append(uris, resource.URI)
+	}
+	if !slices.Equal(uris, []string{currentUserURI, topItemsURI, categoriesURI}) {
+		t.Errorf("got resources %v", uris)
+	}
+	if calls := fake.Calls("GetCategories"); calls != 0 {
+		t.Errorf("got %d GetCategories calls, want none", calls)
+	}
+}
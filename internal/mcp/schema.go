@@ -0,0 +1,296 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tool argument structs describe their JSON Schema with struct tags:
+//
+//	Limit int `json:"limit,omitempty" description:"Maximum number of results" jsonschema:"minimum=1,maximum=50,default=10"`
+//
+// The json tag names the property. description documents it, and the
+// jsonschema tag holds comma-separated options: required, minimum=N,
+// maximum=N, default=V and enum=a|b|c. Defaults are applied before the
+// arguments are decoded, and the other options are enforced by
+// validateArgs. Embedded structs contribute their fields.
+
+var errInvalidArguments = errors.New("invalid arguments")
+
+// fieldSchema holds the parsed tags of one struct field.
+type fieldSchema struct {
+	name        string
+	index       []int
+	description string
+	required    bool
+	minimum     *float64
+	maximum     *float64
+	defaultRaw  string
+	enum        []string
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// structFields returns the JSON properties of a struct type in declaration
+// order, flattening embedded structs.
+func structFields(t reflect.Type) []fieldSchema {
+	var fields []fieldSchema
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for _, embedded := range structFields(f.Type) {
+				embedded.index = append([]int{i}, embedded.index...)
+				fields = append(fields, embedded)
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		field := fieldSchema{
+			name:        name,
+			index:       []int{i},
+			description: f.Tag.Get("description"),
+		}
+		for _, opt := range strings.Split(f.Tag.Get("jsonschema"), ",") {
+			key, value, _ := strings.Cut(opt, "=")
+			switch key {
+			case "required":
+				field.required = true
+			case "minimum":
+				field.minimum = parseBound(t, f, value)
+			case "maximum":
+				field.maximum = parseBound(t, f, value)
+			case "default":
+				field.defaultRaw = value
+			case "enum":
+				field.enum = strings.Split(value, "|")
+			case "":
+			default:
+				panic(fmt.Sprintf("%s.%s: unknown jsonschema option %q", t.Name(), f.Name, key))
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func parseBound(t reflect.Type, f reflect.StructField, value string) *float64 {
+	bound, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		panic(fmt.Sprintf("%s.%s: invalid bound %q", t.Name(), f.Name, value))
+	}
+	return &bound
+}
+
+// inputSchema generates the JSON Schema of a tool's argument struct.
+func inputSchema(t reflect.Type) map[string]interface{} {
+	return typeSchema(t, false, map[reflect.Type]bool{})
+}
+
+// outputSchema generates the JSON Schema of a tool's result. Slices and
+// maps may be null, since Go encodes nil ones that way.
+func outputSchema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		// MCP requires structured content to be an object
+		return nil
+	}
+	return typeSchema(t, true, map[reflect.Type]bool{})
+}
+
+func typeSchema(t reflect.Type, output bool, visiting map[reflect.Type]bool) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	if t == rawMessageType {
+		// Embedded JSON can be any value
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  nullable("array", output && t.Kind() == reflect.Slice),
+			"items": typeSchema(t.Elem(), output, visiting),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 nullable("object", output),
+			"additionalProperties": typeSchema(t.Elem(), output, visiting),
+		}
+	case reflect.Struct:
+		if visiting[t] {
+			// Recursive types are cut off at the second level
+			return map[string]interface{}{"type": "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		properties := map[string]interface{}{}
+		required := []string{}
+		for _, field := range structFields(t) {
+			property := typeSchema(t.FieldByIndex(field.index).Type, output, visiting)
+			field.annotate(property)
+			properties[field.name] = property
+			if field.required {
+				required = append(required, field.name)
+			}
+		}
+		schema := map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		// interface{} and anything else accepts any value
+		return map[string]interface{}{}
+	}
+}
+
+func nullable(typ string, null bool) interface{} {
+	if null {
+		return []string{typ, "null"}
+	}
+	return typ
+}
+
+func (f fieldSchema) annotate(property map[string]interface{}) {
+	description := f.description
+	if f.defaultRaw != "" {
+		property["default"] = f.defaultValue(property["type"])
+		description = strings.TrimSpace(fmt.Sprintf("%s (default: %s)", description, f.defaultRaw))
+	}
+	if description != "" {
+		property["description"] = description
+	}
+	if f.minimum != nil {
+		property["minimum"] = *f.minimum
+	}
+	if f.maximum != nil {
+		property["maximum"] = *f.maximum
+	}
+	if len(f.enum) > 0 {
+		property["enum"] = f.enum
+	}
+}
+
+// defaultValue converts the default tag to the property's JSON type.
+func (f fieldSchema) defaultValue(typ interface{}) interface{} {
+	switch typ {
+	case "integer":
+		if v, err := strconv.ParseInt(f.defaultRaw, 10, 64); err == nil {
+			return v
+		}
+	case "number":
+		if v, err := strconv.ParseFloat(f.defaultRaw, 64); err == nil {
+			return v
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(f.defaultRaw); err == nil {
+			return v
+		}
+	}
+	return f.defaultRaw
+}
+
+// applyDefaults sets fields with a default tag on the struct v points to.
+func applyDefaults(v reflect.Value) {
+	for _, field := range structFields(v.Type()) {
+		if field.defaultRaw == "" {
+			continue
+		}
+		target := v.FieldByIndex(field.index)
+		switch target.Kind() {
+		case reflect.String:
+			target.SetString(field.defaultRaw)
+		case reflect.Bool:
+			if b, err := strconv.ParseBool(field.defaultRaw); err == nil {
+				target.SetBool(b)
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if n, err := strconv.ParseInt(field.defaultRaw, 10, 64); err == nil {
+				target.SetInt(n)
+			}
+		case reflect.Float32, reflect.Float64:
+			if n, err := strconv.ParseFloat(field.defaultRaw, 64); err == nil {
+				target.SetFloat(n)
+			}
+		}
+	}
+}
+
+// validateArgs enforces required, minimum, maximum and enum on decoded
+// arguments. present holds the property names the client sent.
+func validateArgs(v reflect.Value, present map[string]bool) error {
+	for _, field := range structFields(v.Type()) {
+		value := v.FieldByIndex(field.index)
+		if field.required && (!present[field.name] || value.IsZero() && value.Kind() == reflect.String) {
+			return fmt.Errorf("%w: %s is required", errInvalidArguments, field.name)
+		}
+
+		// Optional arguments are pointers, left nil when absent
+		if value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				continue
+			}
+			value = value.Elem()
+		}
+
+		var number float64
+		switch value.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			number = float64(value.Int())
+		case reflect.Float32, reflect.Float64:
+			number = value.Float()
+		case reflect.String:
+			if len(field.enum) > 0 && value.String() != "" && !slices.Contains(field.enum, value.String()) {
+				return fmt.Errorf("%w: %s must be one of %s", errInvalidArguments, field.name, strings.Join(field.enum, ", "))
+			}
+			continue
+		default:
+			continue
+		}
+		if field.minimum != nil && number < *field.minimum {
+			return fmt.Errorf("%w: %s must be at least %v", errInvalidArguments, field.name, *field.minimum)
+		}
+		if field.maximum != nil && number > *field.maximum {
+			return fmt.Errorf("%w: %s must be at most %v", errInvalidArguments, field.name, *field.maximum)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type sortArgs struct {
+	Order string `json:"order" description:"Sort order" jsonschema:"required,enum=asc|desc"`
+}
+
+func TestToolArgumentValidation(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.RegisterTool(NewTool(Tool{Name: "sort", Description: "Sort"}, func(ctx context.Context, args sortArgs) (sortArgs, error) {
+		return args, nil
+	}))
+
+	tests := []struct {
+		tool    string
+		args    interface{}
+		message string
+	}{
+		{"search_tracks", map[string]interface{}{}, "query is required"},
+		{"search_tracks", map[string]interface{}{"query": "queen", "limit": 0}, "limit must be at least 1"},
+		{"search_tracks", map[string]interface{}{"query": "queen", "limit": 51}, "limit must be at most 50"},
+		{"search_tracks", map[string]interface{}{"query": "queen", "limit": "ten"}, "limit must be of type"},
+		{"search_tracks", []string{"queen"}, "arguments must be an object"},
+		{"reorder_playlist_items", map[string]interface{}{"playlist_id": "3cEYpjA9oz9GiPac4AsH4n", "range_start": -1, "insert_before": 0}, "range_start must be at least 0"},
+		{"sort", map[string]interface{}{"order": "random"}, "order must be one of asc, desc"},
+	}
+	for _, test := range tests {
+		err := expectError(t, callTool(t, s, context.Background(), test.tool, test.args), ErrorCodeInvalidParams)
+		if !strings.Contains(err.Message, test.message) {
+			t.Errorf("%s %v: got %q, want it to mention %q", test.tool, test.args, err.Message, test.message)
+		}
+	}
+
+	got := toolOutput[sortArgs](t, callTool(t, s, context.Background(), "sort", map[string]interface{}{"order": "desc"}))
+	if got.Order != "desc" {
+		t.Errorf("got order %q, want desc", got.Order)
+	}
+}
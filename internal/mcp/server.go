@@ -1,17 +1,46 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/anuragkothare/spotify_mcp_server/internal/spotify"
 	"github.com/sirupsen/logrus"
 )
 
 type Server struct {
-	spotifyClient *spotify.Client
+	spotifyClient spotify.API
 	logger        *logrus.Logger
-	tools         map[string]Tool
+	prompts       map[string]*Prompt
+	completions   *completer
+
+	// inFlight holds running requests, keyed by session or API key and
+	// request ID, so notifications/cancelled can stop them.
+	inFlightMu sync.Mutex
+	inFlight   map[string]*inFlightRequest
+
+	sessionsMu sync.Mutex
+	sessions   map[string]*Session
+
+	// tools may change while serving; see RegisterTool.
+	toolsMu sync.RWMutex
+	tools   map[string]Tool
+
+	// toolPolicy restricts which tools clients may use; see ConfigureTools.
+	// Without a policy every tool is enabled.
+	accessMu   sync.RWMutex
+	toolPolicy *toolPolicy
+	// confirm decides how destructive tools are confirmed; see
+	// confirmChange.
+	confirm confirmPolicy
+
+	// journal records applied changes for undo_change; see OpenJournal.
+	journalMu sync.RWMutex
+	journal   *journal
 }
 
 type MCPRequest struct {
@@ -19,6 +48,45 @@ type MCPRequest struct {
 	ID      interface{}     `json:"id"`
 	Method  string          `json:"method"`
 	Params  json.RawMessage `json:"params,omitempty"`
+
+	// notification is set when the decoded message had no id member, as
+	// opposed to an explicit null ID.
+	notification bool
+	// reply is set when the message is the client's response to a request
+	// the server sent.
+	reply *clientReply
+}
+
+// UnmarshalJSON decodes a request and records whether it is a notification.
+func (r *MCPRequest) UnmarshalJSON(data []byte) error {
+	type request MCPRequest
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, (*request)(r)); err != nil {
+		return err
+	}
+	_, hasID := members["id"]
+	r.notification = !hasID
+
+	_, hasMethod := members["method"]
+	result, hasResult := members["result"]
+	replyErr, hasError := members["error"]
+	if !hasMethod && hasID && (hasResult || hasError) {
+		r.reply = &clientReply{Result: result}
+		if hasError {
+			if err := json.Unmarshal(replyErr, &r.reply.Error); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// IsNotification reports whether the request expects no response.
+func (r *MCPRequest) IsNotification() bool {
+	return r.notification
 }
 
 type MCPResponse struct {
@@ -29,103 +97,356 @@ type MCPResponse struct {
 }
 
 type MCPError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
 }
 
 type Tool struct {
-	Name        string                                            `json:"name"`
-	Description string                                            `json:"description"`
-	InputSchema interface{}                                       `json:"inputSchema"`
-	Handler     func(params json.RawMessage) (interface{}, error) `json:"-"`
+	Name        string      `json:"name"`
+	Title       string      `json:"title,omitempty"`
+	Description string      `json:"description"`
+	InputSchema interface{} `json:"inputSchema"`
+	// Group is one of the Group constants; see ConfigureTools.
+	Group string `json:"-"`
+	// OutputSchema describes structuredContent; NewTool generates it.
+	OutputSchema interface{}                                                            `json:"outputSchema,omitempty"`
+	Annotations  *ToolAnnotations                                                       `json:"annotations,omitempty"`
+	Handler      func(ctx context.Context, params json.RawMessage) (interface{}, error) `json:"-"`
+	// Timeout bounds a single call; zero means defaultToolTimeout.
+	Timeout time.Duration `json:"-"`
 }
 
+// defaultToolTimeout is kept below the HTTP server's default write timeout
+// so a timed-out call can still report its error. Tools with a longer
+// Timeout get their write deadline extended; see RequestTimeout.
+const defaultToolTimeout = 25 * time.Second
+
 // ToolInfo represents tool information for JSON responses (without Handler)
 type ToolInfo struct {
-	Name        string      `json:"name"`
-	Description string      `json:"description"`
-	InputSchema interface{} `json:"inputSchema"`
+	Name         string           `json:"name"`
+	Title        string           `json:"title,omitempty"`
+	Description  string           `json:"description"`
+	InputSchema  interface{}      `json:"inputSchema"`
+	OutputSchema interface{}      `json:"outputSchema,omitempty"`
+	Annotations  *ToolAnnotations `json:"annotations,omitempty"`
+}
+
+// ToolAnnotations describe a tool's behavior to clients. They are hints
+// only: clients must not rely on them for safety. All four are always
+// sent, since MCP defaults an omitted destructiveHint and openWorldHint
+// to true.
+type ToolAnnotations struct {
+	// ReadOnlyHint is set when the tool doesn't modify anything.
+	ReadOnlyHint bool `json:"readOnlyHint"`
+	// DestructiveHint is set when a modifying tool may delete or overwrite
+	// data rather than only add to it.
+	DestructiveHint bool `json:"destructiveHint"`
+	// IdempotentHint is set when repeating a call with the same arguments
+	// has no further effect.
+	IdempotentHint bool `json:"idempotentHint"`
+	// OpenWorldHint is set when the tool talks to an external service,
+	// here Spotify.
+	OpenWorldHint bool `json:"openWorldHint"`
 }
 
-func NewServer(spotifyClient *spotify.Client, logger *logrus.Logger) *Server {
+func NewServer(spotifyClient spotify.API, logger *logrus.Logger) *Server {
 	server := &Server{
 		spotifyClient: spotifyClient,
 		logger:        logger,
 		tools:         make(map[string]Tool),
+		prompts:       make(map[string]*Prompt),
+		completions:   newCompleter(),
+		inFlight:      make(map[string]*inFlightRequest),
+		sessions:      make(map[string]*Session),
+		confirm:       confirmPolicy{enabled: true, fallback: fallbackDryRun},
+		journal:       newJournal("", 0),
 	}
 
 	server.registerTools()
+	server.registerPrompts()
+	logger.AddHook(&logHook{server: server})
 	return server
 }
 
 func (s *Server) registerTools() {
-	s.tools["search_tracks"] = Tool{
-		Name:        "search_tracks",
-		Description: "Search for tracks on Spotify",
-		InputSchema: map[string]interface{}{
-			"type": "object",
-			"properties": map[string]interface{}{
-				"query": map[string]interface{}{
-					"type":        "string",
-					"description": "Search query for tracks",
-				},
-				"limit": map[string]interface{}{
-					"type":        "integer",
-					"description": "Maximum number of results (default: 10)",
-					"minimum":     1,
-					"maximum":     50,
-				},
+	for _, tool := range []Tool{
+		NewTool(Tool{
+			Name:        "search_tracks",
+			Group:       GroupCatalog,
+			Title:       "Search tracks",
+			Description: "Search for tracks on Spotify",
+			Annotations: &ToolAnnotations{
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+				OpenWorldHint:  true,
 			},
-			"required": []string{"query"},
-		},
-		Handler: s.handleSearchTracks,
+		}, s.handleSearchTracks),
+		NewTool(Tool{
+			Name:        "search_artists",
+			Group:       GroupCatalog,
+			Title:       "Search artists",
+			Description: "Search for artists on Spotify",
+			Annotations: &ToolAnnotations{
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+				OpenWorldHint:  true,
+			},
+		}, s.handleSearchArtists),
+		NewTool(Tool{
+			Name:        "get_track",
+			Group:       GroupCatalog,
+			Title:       "Get track",
+			Description: "Get detailed information about a specific track",
+			Annotations: &ToolAnnotations{
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+				OpenWorldHint:  true,
+			},
+		}, s.handleGetTrack),
+		NewTool(Tool{
+			Name:        "list_categories",
+			Group:       GroupCatalog,
+			Title:       "List browse categories",
+			Description: "List Spotify browse categories",
+			Annotations: &ToolAnnotations{
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+				OpenWorldHint:  true,
+			},
+		}, s.handleListCategories),
+		NewTool(Tool{
+			Name:        "get_category_playlists",
+			Group:       GroupCatalog,
+			Title:       "Get category playlists",
+			Description: "Get Spotify playlists tagged with a browse category",
+			Annotations: &ToolAnnotations{
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+				OpenWorldHint:  true,
+			},
+		}, s.handleGetCategoryPlaylists),
+		NewTool(Tool{
+			Name:        "get_current_user",
+			Group:       GroupLibrary,
+			Title:       "Get current user",
+			Description: "Get the authorized user's profile, including country, subscription level and explicit content settings",
+			Annotations: &ToolAnnotations{
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+				OpenWorldHint:  true,
+			},
+		}, s.handleGetCurrentUser),
+		NewTool(Tool{
+			Name:        "get_user_profile",
+			Group:       GroupCatalog,
+			Title:       "Get user profile",
+			Description: "Get the public profile of a Spotify user",
+			Annotations: &ToolAnnotations{
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+				OpenWorldHint:  true,
+			},
+		}, s.handleGetUserProfile),
+		NewTool(Tool{
+			Name:        "get_cache_stats",
+			Group:       GroupAdmin,
+			Title:       "Get cache stats",
+			Description: "Report Spotify response cache hits and misses per endpoint",
+			Annotations: &ToolAnnotations{
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+				OpenWorldHint:  false,
+			},
+		}, s.handleGetCacheStats),
+		NewTool(Tool{
+			Name:        "list_my_playlists",
+			Group:       GroupLibrary,
+			Title:       "List my playlists",
+			Description: "List all playlists the authorized user owns or follows, paging through the whole library (reports progress)",
+			Annotations: &ToolAnnotations{
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+				OpenWorldHint:  true,
+			},
+			Timeout: 2 * time.Minute,
+		}, s.handleListMyPlaylists),
+		NewTool(Tool{
+			Name:        "get_playlist_items",
+			Group:       GroupPlaylists,
+			Title:       "Get playlist items",
+			Description: "Get every item in a playlist, in order, with the playlist's current snapshot ID",
+			Annotations: &ToolAnnotations{
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+				OpenWorldHint:  true,
+			},
+		}, s.handleGetPlaylistItems),
+		NewTool(Tool{
+			Name:        "add_playlist_items",
+			Group:       GroupPlaylists,
+			Title:       "Add playlist items",
+			Description: "Add tracks or episodes to a playlist the authorized user owns",
+			Annotations: &ToolAnnotations{
+				OpenWorldHint: true,
+			},
+		}, s.handleAddPlaylistItems),
+		NewTool(Tool{
+			Name:        "remove_playlist_items",
+			Group:       GroupPlaylists,
+			Title:       "Remove playlist items",
+			Description: "Remove every occurrence of tracks or episodes from a playlist the authorized user owns. The user is asked to confirm first",
+			Annotations: &ToolAnnotations{
+				DestructiveHint: true,
+				IdempotentHint:  true,
+				OpenWorldHint:   true,
+			},
+			Timeout: confirmTimeout,
+		}, s.handleRemovePlaylistItems),
+		NewTool(Tool{
+			Name:        "replace_playlist_items",
+			Group:       GroupPlaylists,
+			Title:       "Replace playlist items",
+			Description: "Overwrite the contents of a playlist the authorized user owns, or clear it with an empty list. The user is asked to confirm first",
+			Annotations: &ToolAnnotations{
+				DestructiveHint: true,
+				IdempotentHint:  true,
+				OpenWorldHint:   true,
+			},
+			Timeout: confirmTimeout,
+		}, s.handleReplacePlaylistItems),
+		NewTool(Tool{
+			Name:        "reorder_playlist_items",
+			Group:       GroupPlaylists,
+			Title:       "Reorder playlist items",
+			Description: "Move a range of items within a playlist the authorized user owns",
+			Annotations: &ToolAnnotations{
+				OpenWorldHint: true,
+			},
+		}, s.handleReorderPlaylistItems),
+		NewTool(Tool{
+			Name:        "list_recent_changes",
+			Group:       GroupPlaylists,
+			Title:       "List recent changes",
+			Description: "List the changes this server applied to playlists, newest first, with whether each can still be undone",
+			Annotations: &ToolAnnotations{
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+			},
+		}, s.handleListRecentChanges),
+		NewTool(Tool{
+			Name:        "undo_change",
+			Group:       GroupPlaylists,
+			Title:       "Undo change",
+			Description: "Revert a change from list_recent_changes. Fails if the playlist changed since, unless forced. Undos that remove items ask the user to confirm first",
+			Annotations: &ToolAnnotations{
+				DestructiveHint: true,
+				OpenWorldHint:   true,
+			},
+			Timeout: confirmTimeout,
+		}, s.handleUndoChange),
+		NewTool(Tool{
+			Name:        "list_devices",
+			Group:       GroupPlayback,
+			Title:       "List devices",
+			Description: "List the authorized user's Spotify Connect devices and which one is active. Needs a Premium subscription",
+			Annotations: &ToolAnnotations{
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+				OpenWorldHint:  true,
+			},
+		}, s.handleListDevices),
+	} {
+		s.tools[tool.Name] = tool
 	}
+}
 
-	s.tools["search_artists"] = Tool{
-		Name:        "search_artists",
-		Description: "Search for artists on Spotify",
-		InputSchema: map[string]interface{}{
-			"type": "object",
-			"properties": map[string]interface{}{
-				"query": map[string]interface{}{
-					"type":        "string",
-					"description": "Search query for artists",
-				},
-				"limit": map[string]interface{}{
-					"type":        "integer",
-					"description": "Maximum number of results (default: 10)",
-					"minimum":     1,
-					"maximum":     50,
-				},
-			},
-			"required": []string{"query"},
-		},
-		Handler: s.handleSearchArtists,
+// HandleRequest dispatches a request. It returns nil for notifications
+// and cancelled requests, which have no response.
+func (s *Server) HandleRequest(ctx context.Context, req *MCPRequest) *MCPResponse {
+	session := SessionFromContext(ctx)
+	if session != nil {
+		session.touch()
+	}
+
+	if req.JSONRPC == "2.0" && req.reply != nil {
+		s.handleReply(ctx, req)
+		return nil
 	}
 
-	s.tools["get_track"] = Tool{
-		Name:        "get_track",
-		Description: "Get detailed information about a specific track",
-		InputSchema: map[string]interface{}{
-			"type": "object",
-			"properties": map[string]interface{}{
-				"track_id": map[string]interface{}{
-					"type":        "string",
-					"description": "Spotify track ID",
-				},
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		// Answered even without an ID, since the client can't have meant
+		// to send an invalid notification
+		return &MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &MCPError{
+				Code:    ErrorCodeInvalidRequest,
+				Message: `Invalid request: jsonrpc must be "2.0" and method is required`,
 			},
-			"required": []string{"track_id"},
-		},
-		Handler: s.handleGetTrack,
+		}
+	}
+
+	response := s.dispatch(ctx, req)
+	if req.IsNotification() {
+		return nil
+	}
+	return response
+}
+
+func (s *Server) dispatch(ctx context.Context, req *MCPRequest) *MCPResponse {
+	switch req.Method {
+	case "notifications/cancelled":
+		s.handleCancelled(ctx, req)
+		return nil
+	case "notifications/initialized":
+		return nil
 	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	release := s.trackRequest(ctx, req.ID, cancel)
+	defer release()
+
+	response := s.route(ctx, req)
+	if errors.Is(ctx.Err(), context.Canceled) {
+		// A cancelled request gets no response
+		s.logger.WithContext(ctx).Debugf("Dropping the response to cancelled request %v", req.ID)
+		return nil
+	}
+	return response
 }
 
-func (s *Server) HandleRequest(req *MCPRequest) *MCPResponse {
+// route calls the handler for a request's method.
+func (s *Server) route(ctx context.Context, req *MCPRequest) *MCPResponse {
 	switch req.Method {
+	case "initialize":
+		return s.handleInitialize(ctx, req)
+	case "ping":
+		return &MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Result:  map[string]interface{}{},
+		}
 	case "tools/list":
-		return s.handleListTools(req)
+		return s.handleListTools(ctx, req)
 	case "tools/call":
-		return s.handleToolCall(req)
+		return s.handleToolCall(ctx, req)
+	case "resources/list":
+		return s.handleListResources(ctx, req)
+	case "resources/templates/list":
+		return s.handleListResourceTemplates(ctx, req)
+	case "resources/read":
+		return s.handleReadResource(ctx, req)
+	case "prompts/list":
+		return s.handleListPrompts(ctx, req)
+	case "prompts/get":
+		return s.handleGetPrompt(ctx, req)
+	case "completion/complete":
+		return s.handleComplete(ctx, req)
+	case "logging/setLevel":
+		return s.handleSetLevel(ctx, req)
 	default:
 		return &MCPResponse{
 			JSONRPC: "2.0",
@@ -138,27 +459,7 @@ func (s *Server) HandleRequest(req *MCPRequest) *MCPResponse {
 	}
 }
 
-func (s *Server) handleListTools(req *MCPRequest) *MCPResponse {
-	tools := make([]ToolInfo, 0, len(s.tools))
-	for _, tool := range s.tools {
-		// Convert Tool to ToolInfo (without Handler function)
-		tools = append(tools, ToolInfo{
-			Name:        tool.Name,
-			Description: tool.Description,
-			InputSchema: tool.InputSchema,
-		})
-	}
-
-	return &MCPResponse{
-		JSONRPC: "2.0",
-		ID:      req.ID,
-		Result: map[string]interface{}{
-			"tools": tools,
-		},
-	}
-}
-
-func (s *Server) handleToolCall(req *MCPRequest) *MCPResponse {
+func (s *Server) handleToolCall(ctx context.Context, req *MCPRequest) *MCPResponse {
 	var params struct {
 		Name      string          `json:"name"`
 		Arguments json.RawMessage `json:"arguments"`
@@ -175,8 +476,10 @@ func (s *Server) handleToolCall(req *MCPRequest) *MCPResponse {
 		}
 	}
 
-	tool, exists := s.tools[params.Name]
-	if !exists {
+	tool, exists := s.lookupTool(params.Name)
+	if !exists || !s.toolAllowed(ctx, tool) {
+		// Disabled tools are reported like unknown ones, so clients can't
+		// probe for them
 		return &MCPResponse{
 			JSONRPC: "2.0",
 			ID:      req.ID,
@@ -187,7 +490,54 @@ func (s *Server) handleToolCall(req *MCPRequest) *MCPResponse {
 		}
 	}
 
-	result, err := tool.Handler(params.Arguments)
+	timeout := tool.Timeout
+	if timeout == 0 {
+		timeout = defaultToolTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ctx, staleness := spotify.WithStalenessReport(ctx)
+
+	if token := progressToken(req.Params); token != nil {
+		ctx = withProgress(ctx, &Progress{server: s, ctx: ctx, token: token})
+	}
+
+	result, err := tool.Handler(ctx, params.Arguments)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("tool %s timed out after %s", params.Name, timeout)
+		}
+		mcpErr := &MCPError{
+			Code:    -32603,
+			Message: err.Error(),
+		}
+		var rateErr *spotify.RateLimitError
+		switch {
+		case errors.Is(err, errInvalidArguments):
+			mcpErr.Code = ErrorCodeInvalidParams
+		case errors.Is(err, spotify.ErrUnavailable):
+			mcpErr.Message = "Spotify unavailable: the Spotify API is not responding, try again later"
+			if status := s.spotifyClient.BreakerStatus(); status.RetryAt != nil {
+				mcpErr.Data = map[string]interface{}{
+					"retryAfterSeconds": int(time.Until(*status.RetryAt).Round(time.Second).Seconds()),
+				}
+			}
+		case errors.As(err, &rateErr):
+			mcpErr.Data = map[string]interface{}{
+				"retryAfterSeconds": int(rateErr.RetryAfter.Round(time.Second).Seconds()),
+			}
+		}
+		return &MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   mcpErr,
+		}
+	}
+
+	// Render results as JSON; %+v would print pointer fields such as
+	// is_playable as addresses
+	text, err := json.Marshal(result)
 	if err != nil {
 		return &MCPResponse{
 			JSONRPC: "2.0",
@@ -199,63 +549,187 @@ func (s *Server) handleToolCall(req *MCPRequest) *MCPResponse {
 		}
 	}
 
+	content := []map[string]interface{}{
+		{
+			"type": "text",
+			"text": string(text),
+		},
+	}
+	toolResult := map[string]interface{}{
+		"content": content,
+	}
+	if tool.OutputSchema != nil {
+		toolResult["structuredContent"] = json.RawMessage(text)
+	}
+	if stale, cachedAt := staleness.Stale(); stale {
+		toolResult["content"] = append(content, map[string]interface{}{
+			"type": "text",
+			"text": fmt.Sprintf("Note: Spotify is unavailable; this result is stale cached data from %s.", cachedAt.UTC().Format(time.RFC3339)),
+		})
+		toolResult["_meta"] = staleMeta(cachedAt)
+	}
+
 	return &MCPResponse{
 		JSONRPC: "2.0",
 		ID:      req.ID,
-		Result: map[string]interface{}{
-			"content": []map[string]interface{}{
-				{
-					"type": "text",
-					"text": fmt.Sprintf("%+v", result),
-				},
-			},
-		},
+		Result:  toolResult,
 	}
 }
 
-// Tool handler methods
-func (s *Server) handleSearchTracks(params json.RawMessage) (interface{}, error) {
-	var args struct {
-		Query string `json:"query"`
-		Limit int    `json:"limit"`
+// staleMeta marks a result served from an expired cache entry.
+func staleMeta(cachedAt time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"stale":    true,
+		"cachedAt": cachedAt.UTC().Format(time.RFC3339),
 	}
+}
 
-	if err := json.Unmarshal(params, &args); err != nil {
-		return nil, err
+// RequestTimeout returns how long a request may run when it exceeds the
+// default tool timeout, so the transport can extend its write deadline,
+// or zero otherwise.
+func (s *Server) RequestTimeout(req *MCPRequest) time.Duration {
+	if req.Method != "tools/call" {
+		return 0
 	}
-
-	if args.Limit == 0 {
-		args.Limit = 10
+	var params struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return 0
 	}
+	if tool, ok := s.lookupTool(params.Name); ok && tool.Timeout > defaultToolTimeout {
+		return tool.Timeout
+	}
+	return 0
+}
 
-	return s.spotifyClient.SearchTracks(args.Query, args.Limit)
+// SpotifyStatus reports the health of the upstream Spotify API.
+func (s *Server) SpotifyStatus() spotify.BreakerStatus {
+	return s.spotifyClient.BreakerStatus()
 }
 
-func (s *Server) handleSearchArtists(params json.RawMessage) (interface{}, error) {
-	var args struct {
-		Query string `json:"query"`
-		Limit int    `json:"limit"`
-	}
+// Tool arguments. Their tags generate the tools' input schemas; see
+// schema.go.
 
-	if err := json.Unmarshal(params, &args); err != nil {
-		return nil, err
-	}
+type marketArg struct {
+	Market string `json:"market,omitempty" description:"ISO 3166-1 alpha-2 country code, or from_token for the authorized user's market (default: configured market)"`
+}
 
-	if args.Limit == 0 {
-		args.Limit = 10
-	}
+type pageArgs struct {
+	Limit  int `json:"limit,omitempty" description:"Maximum number of results" jsonschema:"minimum=1,maximum=50,default=20"`
+	Offset int `json:"offset,omitempty" description:"Index of the first result to return" jsonschema:"minimum=0,default=0"`
+}
+
+type searchTracksArgs struct {
+	Query string `json:"query" description:"Search query for tracks" jsonschema:"required"`
+	Limit int    `json:"limit,omitempty" description:"Maximum number of results" jsonschema:"minimum=1,maximum=50,default=10"`
+	marketArg
+}
+
+type searchArtistsArgs struct {
+	Query string `json:"query" description:"Search query for artists" jsonschema:"required"`
+	Limit int    `json:"limit,omitempty" description:"Maximum number of results" jsonschema:"minimum=1,maximum=50,default=10"`
+	marketArg
+}
+
+type getTrackArgs struct {
+	TrackID string `json:"track_id" description:"Spotify track ID" jsonschema:"required"`
+	marketArg
+}
+
+type listCategoriesArgs struct {
+	Locale  string `json:"locale,omitempty" description:"Language and country for category names, e.g. es_MX"`
+	Country string `json:"country,omitempty" description:"ISO 3166-1 alpha-2 country code"`
+	pageArgs
+	marketArg
+}
+
+type getCategoryPlaylistsArgs struct {
+	CategoryID string `json:"category_id" description:"Spotify category ID, e.g. party" jsonschema:"required"`
+	Country    string `json:"country,omitempty" description:"ISO 3166-1 alpha-2 country code"`
+	pageArgs
+	marketArg
+}
+
+type getUserProfileArgs struct {
+	UserID string `json:"user_id" description:"Spotify user ID" jsonschema:"required"`
+}
+
+type listMyPlaylistsArgs struct {
+	Max int `json:"max,omitempty" description:"Maximum number of playlists to return" jsonschema:"minimum=1,maximum=2000,default=500"`
+}
 
-	return s.spotifyClient.SearchArtists(args.Query, args.Limit)
+// noArgs is the argument struct of tools without parameters.
+type noArgs struct{}
+
+// Tool handler methods
+func (s *Server) handleSearchTracks(ctx context.Context, args searchTracksArgs) (*spotify.SearchResult, error) {
+	return s.spotifyClient.SearchTracks(ctx, args.Query, args.Limit, args.Market)
 }
 
-func (s *Server) handleGetTrack(params json.RawMessage) (interface{}, error) {
-	var args struct {
-		TrackID string `json:"track_id"`
+func (s *Server) handleSearchArtists(ctx context.Context, args searchArtistsArgs) (*spotify.ArtistSearchResult, error) {
+	return s.spotifyClient.SearchArtists(ctx, args.Query, args.Limit, args.Market)
+}
+
+func (s *Server) handleGetTrack(ctx context.Context, args getTrackArgs) (*spotify.Track, error) {
+	return s.spotifyClient.GetTrack(ctx, args.TrackID, args.Market)
+}
+
+func (s *Server) handleListCategories(ctx context.Context, args listCategoriesArgs) (*spotify.CategoryPage, error) {
+	return s.spotifyClient.GetCategories(ctx, spotify.BrowseOptions{
+		Locale:  args.Locale,
+		Country: args.Country,
+		Market:  args.Market,
+		Limit:   args.Limit,
+		Offset:  args.Offset,
+	})
+}
+
+func (s *Server) handleGetCategoryPlaylists(ctx context.Context, args getCategoryPlaylistsArgs) (*spotify.PlaylistPage, error) {
+	return s.spotifyClient.GetCategoryPlaylists(ctx, args.CategoryID, spotify.BrowseOptions{
+		Country: args.Country,
+		Market:  args.Market,
+		Limit:   args.Limit,
+		Offset:  args.Offset,
+	})
+}
+
+func (s *Server) handleGetCurrentUser(ctx context.Context, args noArgs) (*spotify.CurrentUser, error) {
+	return s.spotifyClient.GetCurrentUser(ctx)
+}
+
+func (s *Server) handleGetUserProfile(ctx context.Context, args getUserProfileArgs) (*spotify.User, error) {
+	return s.spotifyClient.GetUserProfile(ctx, args.UserID)
+}
+
+func (s *Server) handleGetCacheStats(ctx context.Context, args noArgs) (*spotify.CacheStats, error) {
+	stats := s.spotifyClient.CacheStats()
+	if stats == nil {
+		return nil, fmt.Errorf("response cache is disabled")
 	}
+	return stats, nil
+}
 
-	if err := json.Unmarshal(params, &args); err != nil {
-		return nil, err
+func (s *Server) handleListMyPlaylists(ctx context.Context, args listMyPlaylistsArgs) (*spotify.PlaylistPage, error) {
+	progress := ProgressFromContext(ctx)
+	result := &spotify.PlaylistPage{Playlists: []spotify.Playlist{}}
+	for offset := 0; offset < args.Max; {
+		limit := min(50, args.Max-offset)
+		page, err := s.spotifyClient.GetMyPlaylists(ctx, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+		result.Playlists = append(result.Playlists, page.Playlists...)
+		result.Total = page.Total
+
+		offset += limit
+		total := min(page.Total, args.Max)
+		progress.Report(min(offset, total), total, fmt.Sprintf("Fetched %d of %d playlists", min(offset, total), total))
+		if offset >= page.Total {
+			break
+		}
 	}
+	result.Limit = len(result.Playlists)
 
-	return s.spotifyClient.GetTrack(args.TrackID)
+	return result, nil
 }
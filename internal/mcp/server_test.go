@@ -0,0 +1,118 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+	"github.com/anuragkothare/spotify_mcp_server/internal/spotify/spotifytest"
+	"github.com/sirupsen/logrus"
+)
+
+// newTestServer returns a server over the fake with every tool group
+// enabled.
+func newTestServer(t *testing.T) (*Server, *spotifytest.Fake) {
+	t.Helper()
+	fake := spotifytest.NewFake()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	s := NewServer(fake, logger)
+	if err := s.ConfigureTools(config.ToolsConfig{Groups: ToolGroups()}); err != nil {
+		t.Fatalf("ConfigureTools: %v", err)
+	}
+	return s, fake
+}
+
+// newRequest decodes a request the way the HTTP handlers do. A nil id
+// makes it a notification.
+func newRequest(t *testing.T, id interface{}, method string, params interface{}) *MCPRequest {
+	t.Helper()
+	message := map[string]interface{}{"jsonrpc": "2.0", "method": method}
+	if id != nil {
+		message["id"] = id
+	}
+	if params != nil {
+		message["params"] = params
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	var req MCPRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatalf("unmarshal request: %v", err)
+	}
+	return &req
+}
+
+func callTool(t *testing.T, s *Server, ctx context.Context, name string, args interface{}) *MCPResponse {
+	t.Helper()
+	return s.HandleRequest(ctx, newRequest(t, 1, "tools/call", map[string]interface{}{
+		"name":      name,
+		"arguments": args,
+	}))
+}
+
+// toolOutput decodes a tool call's structured content.
+func toolOutput[T any](t *testing.T, response *MCPResponse) T {
+	t.Helper()
+	var result struct {
+		StructuredContent T `json:"structuredContent"`
+	}
+	decodeResult(t, response, &result)
+	return result.StructuredContent
+}
+
+// decodeResult decodes a successful response's result into v.
+func decodeResult(t *testing.T, response *MCPResponse, v interface{}) {
+	t.Helper()
+	if response == nil {
+		t.Fatal("got no response")
+	}
+	if response.Error != nil {
+		t.Fatalf("got error %d: %s", response.Error.Code, response.Error.Message)
+	}
+	data, err := json.Marshal(response.Result)
+	if err != nil {
+		t.Fatalf("marshal result: %v", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("unmarshal result %s: %v", data, err)
+	}
+}
+
+func expectError(t *testing.T, response *MCPResponse, code int) *MCPError {
+	t.Helper()
+	if response == nil {
+		t.Fatal("got no response")
+	}
+	if response.Error == nil {
+		t.Fatalf("got result %+v, want error %d", response.Result, code)
+	}
+	if response.Error.Code != code {
+		t.Fatalf("got error %d (%s), want %d", response.Error.Code, response.Error.Message, code)
+	}
+	return response.Error
+}
+
+func TestHandleRequestInvalid(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	for _, req := range []*MCPRequest{
+		{JSONRPC: "1.0", ID: 1.0, Method: "tools/list"},
+		{JSONRPC: "2.0", ID: 1.0},
+	} {
+		expectError(t, s.HandleRequest(context.Background(), req), ErrorCodeInvalidRequest)
+	}
+	expectError(t, s.HandleRequest(context.Background(), newRequest(t, 1, "no/such/method", nil)), ErrorCodeMethodNotFound)
+}
+
+func TestHandleRequestNotification(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	if response := s.HandleRequest(context.Background(), newRequest(t, nil, "tools/list", nil)); response != nil {
+		t.Errorf("notification got response %+v", response)
+	}
+}
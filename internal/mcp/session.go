@@ -0,0 +1,231 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sessionOutboxSize bounds the messages queued for a session's stream;
+// further messages are dropped until the client reads.
+const sessionOutboxSize = 64
+
+// MCPNotification is a JSON-RPC notification sent to the client.
+type MCPNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// Session is a client connection established by initialize and identified
+// by the Mcp-Session-Id header. Server-initiated messages are queued on
+// its outbox and delivered over the client's GET stream.
+type Session struct {
+	ID         string
+	ClientInfo ClientInfo
+	CreatedAt  time.Time
+	// scope is the API key the session was created with, if any
+	scope *KeyScope
+
+	outbox chan []byte
+	done   chan struct{}
+	nextID atomic.Int64
+
+	mu        sync.Mutex
+	streaming bool
+	lastSeen  time.Time
+	pinging   bool
+	// pending holds the reply channels of server-initiated requests.
+	pending map[string]chan *clientReply
+	// elicitation is set when the client declared it can be asked for
+	// input; see confirmChange.
+	elicitation bool
+	// logLevel is one more than the index of the minimum severity in
+	// logLevels, or zero when logging is off.
+	logLevel int
+}
+
+type sessionContextKey struct{}
+type sinkContextKey struct{}
+
+// WithSession attaches the session a request belongs to.
+func WithSession(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, session)
+}
+
+// SessionFromContext returns the request's session, or nil for requests
+// made without one.
+func SessionFromContext(ctx context.Context) *Session {
+	session, _ := ctx.Value(sessionContextKey{}).(*Session)
+	return session
+}
+
+// WithMessageSink routes messages about a request, such as progress, to
+// sink instead of the session outbox, e.g. to stream them on the request's
+// own response.
+func WithMessageSink(ctx context.Context, sink func(message interface{})) context.Context {
+	return context.WithValue(ctx, sinkContextKey{}, sink)
+}
+
+// NewSession creates and registers a session, bound to the API key the
+// request that created it was authenticated with.
+func (s *Server) NewSession(ctx context.Context) *Session {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	session := &Session{
+		ID:        hex.EncodeToString(id),
+		CreatedAt: time.Now(),
+		outbox:    make(chan []byte, sessionOutboxSize),
+		done:      make(chan struct{}),
+		lastSeen:  time.Now(),
+		pending:   make(map[string]chan *clientReply),
+		scope:     keyScopeFromContext(ctx),
+	}
+
+	s.sessionsMu.Lock()
+	s.sessions[session.ID] = session
+	s.sessionsMu.Unlock()
+	return session
+}
+
+// Session looks up a session by ID for a request. Only requests made with
+// the API key that created the session find it, so a leaked session ID is
+// no use with another key.
+func (s *Server) Session(ctx context.Context, id string) (*Session, bool) {
+	s.sessionsMu.Lock()
+	session, ok := s.sessions[id]
+	s.sessionsMu.Unlock()
+	if !ok || session.scope.Name() != keyScopeFromContext(ctx).Name() {
+		return nil, false
+	}
+	return session, true
+}
+
+// CloseSession forgets a session, cancelling its in-flight requests and
+// pending server requests. It reports whether the session existed.
+func (s *Server) CloseSession(id string) bool {
+	s.sessionsMu.Lock()
+	session, ok := s.sessions[id]
+	if !ok {
+		s.sessionsMu.Unlock()
+		return false
+	}
+	delete(s.sessions, id)
+	close(session.done)
+	s.sessionsMu.Unlock()
+
+	// Requests still running for the session have no one to answer
+	s.inFlightMu.Lock()
+	for key, entry := range s.inFlight {
+		if strings.HasPrefix(key, id+"/") {
+			entry.cancel()
+		}
+	}
+	s.inFlightMu.Unlock()
+	return true
+}
+
+// CloseAllSessions closes every session, ending their streams, e.g. so a
+// graceful shutdown doesn't wait on them.
+func (s *Server) CloseAllSessions() {
+	s.sessionsMu.Lock()
+	ids := make([]string, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	s.sessionsMu.Unlock()
+
+	for _, id := range ids {
+		s.CloseSession(id)
+	}
+}
+
+// Done is closed when the session is closed.
+func (sess *Session) Done() <-chan struct{} {
+	return sess.done
+}
+
+func (sess *Session) touch() {
+	sess.mu.Lock()
+	sess.lastSeen = time.Now()
+	sess.mu.Unlock()
+}
+
+func (sess *Session) isStreaming() bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.streaming
+}
+
+// Send queues a message for the session's stream without blocking. It
+// reports false when the message was dropped.
+func (sess *Session) Send(message interface{}) bool {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return false
+	}
+	select {
+	case sess.outbox <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stream claims the session's outbox for a GET stream. Only one stream may
+// be open at a time; the returned release function must be called when
+// the stream ends.
+func (sess *Session) Stream() (<-chan []byte, func(), bool) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.streaming {
+		return nil, nil, false
+	}
+	sess.streaming = true
+	return sess.outbox, func() {
+		sess.mu.Lock()
+		sess.streaming = false
+		sess.mu.Unlock()
+	}, true
+}
+
+// broadcast sends a notification to every session.
+func (s *Server) broadcast(method string, params interface{}) {
+	message := &MCPNotification{JSONRPC: "2.0", Method: method, Params: params}
+
+	s.sessionsMu.Lock()
+	sessions := make([]*Session, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	s.sessionsMu.Unlock()
+
+	for _, session := range sessions {
+		if !session.Send(message) {
+			s.logger.Debugf("Dropped %s for session %s", method, session.ID)
+		}
+	}
+}
+
+// notify sends a notification about the current request to the request's
+// sink if it has one, otherwise to its session. Without either the
+// notification is dropped.
+func (s *Server) notify(ctx context.Context, method string, params interface{}) {
+	message := &MCPNotification{JSONRPC: "2.0", Method: method, Params: params}
+
+	if sink, ok := ctx.Value(sinkContextKey{}).(func(interface{})); ok {
+		sink(message)
+		return
+	}
+	if session := SessionFromContext(ctx); session != nil {
+		if !session.Send(message) {
+			s.logger.Debugf("Dropped %s for session %s", method, session.ID)
+		}
+	}
+}
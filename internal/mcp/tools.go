@@ -0,0 +1,169 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// toolsPageSize is the number of tools returned per tools/list page.
+const toolsPageSize = 50
+
+// NewTool completes tool from a typed handler: the input schema is
+// generated from the argument struct A and the output schema from the
+// result R (see schema.go), and the handler receives arguments that are
+// decoded, defaulted and validated against them.
+func NewTool[A, R any](tool Tool, handler func(ctx context.Context, args A) (R, error)) Tool {
+	argsType := reflect.TypeOf((*A)(nil)).Elem()
+	if argsType.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("tool %s: arguments must be a struct, got %s", tool.Name, argsType))
+	}
+
+	tool.InputSchema = inputSchema(argsType)
+	if schema := outputSchema(reflect.TypeOf((*R)(nil)).Elem()); schema != nil {
+		tool.OutputSchema = schema
+	}
+	tool.Handler = func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		args, err := decodeArgs[A](params)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, args)
+	}
+	return tool
+}
+
+// decodeArgs decodes tool arguments onto a defaulted A and validates them.
+func decodeArgs[A any](params json.RawMessage) (A, error) {
+	var args A
+	v := reflect.ValueOf(&args).Elem()
+	applyDefaults(v)
+
+	present := map[string]bool{}
+	if len(bytes.TrimSpace(params)) > 0 && !bytes.Equal(bytes.TrimSpace(params), []byte("null")) {
+		var members map[string]json.RawMessage
+		if err := json.Unmarshal(params, &members); err != nil {
+			return args, fmt.Errorf("%w: arguments must be an object", errInvalidArguments)
+		}
+		for name, value := range members {
+			// An explicit null means the argument was left out
+			if !bytes.Equal(value, []byte("null")) {
+				present[name] = true
+			}
+		}
+		if err := json.Unmarshal(params, &args); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) && typeErr.Field != "" {
+				expected := typeSchema(typeErr.Type, false, map[reflect.Type]bool{})["type"]
+				return args, fmt.Errorf("%w: %s must be of type %v", errInvalidArguments, typeErr.Field, expected)
+			}
+			return args, fmt.Errorf("%w: %v", errInvalidArguments, err)
+		}
+	}
+
+	if err := validateArgs(v, present); err != nil {
+		return args, err
+	}
+	return args, nil
+}
+
+// RegisterTool adds a tool, replacing any tool with the same name, and
+// tells sessions the tool list changed.
+func (s *Server) RegisterTool(tool Tool) {
+	s.toolsMu.Lock()
+	s.tools[tool.Name] = tool
+	s.toolsMu.Unlock()
+
+	s.broadcast("notifications/tools/list_changed", nil)
+}
+
+// RemoveTool removes a tool. It reports whether the tool existed.
+func (s *Server) RemoveTool(name string) bool {
+	s.toolsMu.Lock()
+	_, ok := s.tools[name]
+	delete(s.tools, name)
+	s.toolsMu.Unlock()
+
+	if ok {
+		s.broadcast("notifications/tools/list_changed", nil)
+	}
+	return ok
+}
+
+func (s *Server) lookupTool(name string) (Tool, bool) {
+	s.toolsMu.RLock()
+	defer s.toolsMu.RUnlock()
+	tool, ok := s.tools[name]
+	return tool, ok
+}
+
+// sortedTools returns the registered tools ordered by name.
+func (s *Server) sortedTools() []Tool {
+	s.toolsMu.RLock()
+	tools := make([]Tool, 0, len(s.tools))
+	for _, tool := range s.tools {
+		tools = append(tools, tool)
+	}
+	s.toolsMu.RUnlock()
+
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
+
+// handleListTools returns tools in name order. The cursor is the name of
+// the last tool on the previous page, so paging stays consistent when
+// tools are added or removed in between.
+func (s *Server) handleListTools(ctx context.Context, req *MCPRequest) *MCPResponse {
+	var params ListToolsRequest
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return &MCPResponse{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error: &MCPError{
+					Code:    ErrorCodeInvalidParams,
+					Message: "Invalid parameters",
+				},
+			}
+		}
+	}
+
+	var all []Tool
+	for _, tool := range s.sortedTools() {
+		if s.toolAllowed(ctx, tool) {
+			all = append(all, tool)
+		}
+	}
+	start := sort.Search(len(all), func(i int) bool { return all[i].Name > params.Cursor })
+	end := min(start+toolsPageSize, len(all))
+
+	tools := make([]ToolInfo, 0, end-start)
+	for _, tool := range all[start:end] {
+		// Convert Tool to ToolInfo (without Handler function)
+		tools = append(tools, ToolInfo{
+			Name:         tool.Name,
+			Title:        tool.Title,
+			Description:  tool.Description,
+			InputSchema:  tool.InputSchema,
+			OutputSchema: tool.OutputSchema,
+			Annotations:  tool.Annotations,
+		})
+	}
+
+	result := map[string]interface{}{
+		"tools": tools,
+	}
+	if end < len(all) {
+		result["nextCursor"] = all[end-1].Name
+	}
+
+	return &MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  result,
+	}
+}
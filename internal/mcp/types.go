@@ -85,9 +85,10 @@ type CallToolResponse struct {
 
 // Content represents MCP content
 type Content struct {
-	Type string      `json:"type"`
-	Text string      `json:"text,omitempty"`
-	Data interface{} `json:"data,omitempty"`
+	Type     string           `json:"type"`
+	Text     string           `json:"text,omitempty"`
+	Data     interface{}      `json:"data,omitempty"`
+	Resource *ResourceContent `json:"resource,omitempty"`
 }
 
 // ListResourcesRequest represents a list resources request
@@ -101,6 +102,25 @@ type ListResourcesResponse struct {
 	NextCursor string      `json:"nextCursor,omitempty"`
 }
 
+// ResourceTemplate represents a parameterized resource URI (RFC 6570)
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ListResourceTemplatesRequest represents a list resource templates request
+type ListResourceTemplatesRequest struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// ListResourceTemplatesResponse represents a list resource templates response
+type ListResourceTemplatesResponse struct {
+	ResourceTemplates []*ResourceTemplate `json:"resourceTemplates"`
+	NextCursor        string              `json:"nextCursor,omitempty"`
+}
+
 // ReadResourceRequest represents a read resource request
 type ReadResourceRequest struct {
 	URI string `json:"uri"`
@@ -108,7 +128,8 @@ type ReadResourceRequest struct {
 
 // ReadResourceResponse represents a read resource response
 type ReadResourceResponse struct {
-	Contents []ResourceContent `json:"contents"`
+	Contents []ResourceContent      `json:"contents"`
+	Meta     map[string]interface{} `json:"_meta,omitempty"`
 }
 
 // ResourceContent represents resource content
@@ -119,6 +140,82 @@ type ResourceContent struct {
 	Blob     []byte `json:"blob,omitempty"`
 }
 
+// PromptArgument describes an argument a prompt accepts
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PromptInfo represents a prompt in a list prompts response
+type PromptInfo struct {
+	Name        string           `json:"name"`
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// ListPromptsRequest represents a list prompts request
+type ListPromptsRequest struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// ListPromptsResponse represents a list prompts response
+type ListPromptsResponse struct {
+	Prompts    []PromptInfo `json:"prompts"`
+	NextCursor string       `json:"nextCursor,omitempty"`
+}
+
+// GetPromptRequest represents a get prompt request
+type GetPromptRequest struct {
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+// PromptMessage represents a message in a get prompt response
+type PromptMessage struct {
+	Role    string  `json:"role"`
+	Content Content `json:"content"`
+}
+
+// GetPromptResponse represents a get prompt response
+type GetPromptResponse struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// CompletionReference identifies what is being completed: a prompt
+// ("ref/prompt" with Name) or a resource template ("ref/resource" with URI)
+type CompletionReference struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+	URI  string `json:"uri,omitempty"`
+}
+
+// CompletionArgument is the argument being completed and its partial value
+type CompletionArgument struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CompleteRequest represents a completion/complete request
+type CompleteRequest struct {
+	Ref      CompletionReference `json:"ref"`
+	Argument CompletionArgument  `json:"argument"`
+}
+
+// Completion holds suggested values, at most 100
+type Completion struct {
+	Values  []string `json:"values"`
+	Total   int      `json:"total,omitempty"`
+	HasMore bool     `json:"hasMore,omitempty"`
+}
+
+// CompleteResponse represents a completion/complete response
+type CompleteResponse struct {
+	Completion Completion `json:"completion"`
+}
+
 // Common MCP error codes
 const (
 	ErrorCodeInvalidRequest      = -32600
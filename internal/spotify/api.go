@@ -0,0 +1,35 @@
+package spotify
+
+import "context"
+
+// API is the set of Spotify operations the MCP server depends on. Client
+// implements it against the Web API; spotifytest.Fake implements it from
+// fixtures for offline use.
+type API interface {
+	SearchTracks(ctx context.Context, query string, limit int, market string) (*SearchResult, error)
+	SearchArtists(ctx context.Context, query string, limit int, market string) (*ArtistSearchResult, error)
+	GetTrack(ctx context.Context, trackID string, market string) (*Track, error)
+	GetArtist(ctx context.Context, artistID string) (*Artist, error)
+	GetAlbum(ctx context.Context, albumID string, market string) (*Album, error)
+	GetPlaylist(ctx context.Context, playlistID string, market string) (*Playlist, error)
+	GetCategories(ctx context.Context, opts BrowseOptions) (*CategoryPage, error)
+	GetCategoryPlaylists(ctx context.Context, categoryID string, opts BrowseOptions) (*PlaylistPage, error)
+	GetCurrentUser(ctx context.Context) (*CurrentUser, error)
+	GetUserProfile(ctx context.Context, userID string) (*User, error)
+	GetMyPlaylists(ctx context.Context, limit, offset int) (*PlaylistPage, error)
+	GetTopItems(ctx context.Context, timeRange string, limit int) (*TopItems, error)
+	GetDevices(ctx context.Context) ([]Device, error)
+
+	GetPlaylistItems(ctx context.Context, playlistID string) (*PlaylistItems, error)
+	AddPlaylistItems(ctx context.Context, playlistID string, uris []string, position *int) (string, error)
+	RemovePlaylistItems(ctx context.Context, playlistID string, uris []string, snapshotID string) (string, error)
+	RemovePlaylistItemsAt(ctx context.Context, playlistID string, items []PlaylistItemPosition, snapshotID string) (string, error)
+	ReplacePlaylistItems(ctx context.Context, playlistID string, uris []string) (string, error)
+	ReorderPlaylistItems(ctx context.Context, playlistID string, rangeStart, rangeLength, insertBefore int, snapshotID string) (string, error)
+
+	UserAuthorized() bool
+	CacheStats() *CacheStats
+	BreakerStatus() BreakerStatus
+}
+
+var _ API = (*Client)(nil)
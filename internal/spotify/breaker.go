@@ -0,0 +1,202 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+)
+
+const (
+	defaultFailureThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// Breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// ErrUnavailable is returned without contacting Spotify while the circuit
+// breaker is open.
+var ErrUnavailable = errors.New("Spotify unavailable")
+
+// BreakerStatus describes the circuit breaker for health reporting.
+type BreakerStatus struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
+}
+
+// breakerTransport fails fast after a run of consecutive upstream failures.
+// After the cooldown a single probe request is let through; its outcome
+// closes the breaker or opens it again.
+type breakerTransport struct {
+	next      http.RoundTripper
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+}
+
+func newBreakerTransport(next http.RoundTripper, cfg config.BreakerConfig) *breakerTransport {
+	threshold := cfg.FailureThreshold
+	if threshold <= 0 {
+		threshold = defaultFailureThreshold
+	}
+	cooldown := time.Duration(cfg.Cooldown) * time.Second
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+	return &breakerTransport{
+		next:      next,
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     BreakerClosed,
+	}
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.allow(); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	t.record(req.Context(), resp, err)
+	return resp, err
+}
+
+func (t *breakerTransport) allow() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch t.state {
+	case BreakerOpen:
+		retryAt := t.openedAt.Add(t.cooldown)
+		if time.Now().Before(retryAt) {
+			return fmt.Errorf("%w: %d consecutive failures, retrying in %s",
+				ErrUnavailable, t.failures, time.Until(retryAt).Round(time.Second))
+		}
+		t.state = BreakerHalfOpen
+		return nil
+	case BreakerHalfOpen:
+		// A probe is already in flight
+		return fmt.Errorf("%w: checking whether Spotify has recovered", ErrUnavailable)
+	}
+	return nil
+}
+
+func (t *breakerTransport) record(ctx context.Context, resp *http.Response, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !isUpstreamFailure(ctx, resp, err) {
+		if t.state == BreakerHalfOpen && err != nil {
+			// The probe was abandoned; let the next request probe again
+			t.state = BreakerOpen
+			return
+		}
+		t.state = BreakerClosed
+		t.failures = 0
+		return
+	}
+
+	t.failures++
+	if t.state == BreakerHalfOpen || t.failures >= t.threshold {
+		t.state = BreakerOpen
+		t.openedAt = time.Now()
+	}
+}
+
+// isUpstreamFailure reports whether the outcome says Spotify itself is
+// unhealthy. Rate limiting and requests the caller cancelled do not count.
+// Running out of time does: the base transport has no response header
+// timeout, so a hung Spotify only shows up as expired deadlines.
+func isUpstreamFailure(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		var rateErr *RateLimitError
+		switch {
+		case errors.As(err, &rateErr):
+			return false
+		case errors.Is(ctx.Err(), context.Canceled), errors.Is(err, context.Canceled):
+			return false
+		}
+		return true
+	}
+	return resp.StatusCode >= 500
+}
+
+func (t *breakerTransport) Status() BreakerStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := BreakerStatus{
+		State:               t.state,
+		ConsecutiveFailures: t.failures,
+	}
+	if t.state != BreakerClosed {
+		openedAt := t.openedAt
+		retryAt := openedAt.Add(t.cooldown)
+		status.OpenedAt = &openedAt
+		status.RetryAt = &retryAt
+	}
+	return status
+}
+
+// Staleness records whether any response used while serving a request came
+// from an expired cache entry because Spotify could not be reached.
+type Staleness struct {
+	mu       sync.Mutex
+	stale    bool
+	cachedAt time.Time
+}
+
+type stalenessKey struct{}
+
+// WithStalenessReport returns a context that collects staleness for the
+// Spotify calls made with it.
+func WithStalenessReport(ctx context.Context) (context.Context, *Staleness) {
+	report := &Staleness{}
+	return context.WithValue(ctx, stalenessKey{}, report), report
+}
+
+// Stale reports whether stale data was served and the oldest cache time.
+func (s *Staleness) Stale() (bool, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stale, s.cachedAt
+}
+
+func (s *Staleness) mark(cachedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.stale || cachedAt.Before(s.cachedAt) {
+		s.cachedAt = cachedAt
+	}
+	s.stale = true
+}
+
+func markStale(ctx context.Context, cachedAt time.Time) {
+	if report, ok := ctx.Value(stalenessKey{}).(*Staleness); ok {
+		report.mark(cachedAt)
+	}
+}
+
+// copyStaleness forwards staleness collected in from into the report
+// attached to ctx, if any.
+func copyStaleness(ctx context.Context, from *Staleness) {
+	if stale, cachedAt := from.Stale(); stale {
+		markStale(ctx, cachedAt)
+	}
+}
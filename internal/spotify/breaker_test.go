@@ -0,0 +1,56 @@
+package spotify
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+)
+
+func TestBreakerIgnoresCallerErrors(t *testing.T) {
+	stub := &stubTransport{statuses: []int{200}, err: context.Canceled}
+	breaker := newBreakerTransport(stub, config.BreakerConfig{FailureThreshold: 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for range 3 {
+		breaker.RoundTrip(newTestRequest(t, ctx, http.MethodGet))
+	}
+	if state := breaker.Status().State; state != BreakerClosed {
+		t.Errorf("cancelled requests left the breaker %s", state)
+	}
+
+	// Rate limiting doesn't say Spotify is down either
+	stub.err = &RateLimitError{}
+	for range 3 {
+		breaker.RoundTrip(newTestRequest(t, context.Background(), http.MethodGet))
+	}
+	if state := breaker.Status().State; state != BreakerClosed {
+		t.Errorf("rate limiting left the breaker %s", state)
+	}
+
+	// Timeouts do, since a hung Spotify never answers with a 5xx
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	stub.err = context.DeadlineExceeded
+	for range 2 {
+		breaker.RoundTrip(newTestRequest(t, ctx, http.MethodGet))
+	}
+	if state := breaker.Status().State; state != BreakerOpen {
+		t.Errorf("got breaker %s after repeated timeouts, want open", state)
+	}
+
+	breaker = newBreakerTransport(stub, config.BreakerConfig{FailureThreshold: 2})
+	stub.err = nil
+	stub.statuses = []int{503}
+	for range 2 {
+		if resp, err := breaker.RoundTrip(newTestRequest(t, context.Background(), http.MethodGet)); err == nil {
+			resp.Body.Close()
+		}
+	}
+	if state := breaker.Status().State; state != BreakerOpen {
+		t.Errorf("got breaker %s after repeated 5xx, want open", state)
+	}
+}
@@ -0,0 +1,135 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// BrowseOptions narrows browse requests to a locale and country. Market is
+// used as the country when Country is empty.
+type BrowseOptions struct {
+	Locale  string
+	Country string
+	Market  string
+	Limit   int
+	Offset  int
+}
+
+func (o BrowseOptions) requestOptions() []spotify.RequestOption {
+	var opts []spotify.RequestOption
+	if o.Locale != "" {
+		opts = append(opts, spotify.Locale(o.Locale))
+	}
+	if o.Country != "" {
+		opts = append(opts, spotify.Country(o.Country))
+	}
+	if o.Limit > 0 {
+		opts = append(opts, spotify.Limit(o.Limit))
+	}
+	if o.Offset > 0 {
+		opts = append(opts, spotify.Offset(o.Offset))
+	}
+	return opts
+}
+
+// browseCountry fills in the country for browse endpoints, which accept a
+// country code but not from_token.
+func (c *Client) browseCountry(ctx context.Context, opts BrowseOptions) BrowseOptions {
+	if opts.Country != "" {
+		return opts
+	}
+	market := opts.Market
+	if market == "" {
+		market = c.defaultMarket
+	}
+	if market == "" || strings.EqualFold(market, marketFromToken) {
+		market = c.UserMarket(ctx)
+	}
+	opts.Country = strings.ToUpper(market)
+	return opts
+}
+
+func (c *Client) GetCategories(ctx context.Context, opts BrowseOptions) (*CategoryPage, error) {
+	opts = c.browseCountry(ctx, opts)
+
+	key := flightKey("GetCategories", opts.Locale, opts.Country, opts.Limit, opts.Offset)
+	return coalesce(ctx, &c.flight, key, func(ctx context.Context) (*CategoryPage, error) {
+		return c.getCategories(ctx, opts)
+	})
+}
+
+func (c *Client) getCategories(ctx context.Context, opts BrowseOptions) (*CategoryPage, error) {
+	page, err := c.client.GetCategories(ctx, opts.requestOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	categories := make([]Category, len(page.Categories))
+	for i, category := range page.Categories {
+		categories[i] = Category{
+			ID:   category.ID,
+			Name: category.Name,
+		}
+	}
+
+	return &CategoryPage{
+		Categories: categories,
+		Total:      int(page.Total),
+		Limit:      int(page.Limit),
+		Offset:     int(page.Offset),
+	}, nil
+}
+
+func (c *Client) GetCategoryPlaylists(ctx context.Context, categoryID string, opts BrowseOptions) (*PlaylistPage, error) {
+	// Locale is not supported by this endpoint
+	opts.Locale = ""
+	opts = c.browseCountry(ctx, opts)
+
+	key := flightKey("GetCategoryPlaylists", categoryID, opts.Country, opts.Limit, opts.Offset)
+	return coalesce(ctx, &c.flight, key, func(ctx context.Context) (*PlaylistPage, error) {
+		return c.getCategoryPlaylists(ctx, categoryID, opts)
+	})
+}
+
+func (c *Client) getCategoryPlaylists(ctx context.Context, categoryID string, opts BrowseOptions) (*PlaylistPage, error) {
+	page, err := c.client.GetCategoryPlaylists(ctx, categoryID, opts.requestOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category playlists: %w", err)
+	}
+
+	playlists := make([]Playlist, 0, len(page.Playlists))
+	for _, playlist := range page.Playlists {
+		// Spotify returns null entries for playlists that are no longer available
+		if playlist.ID == "" {
+			continue
+		}
+		playlists = append(playlists, newPlaylist(playlist))
+	}
+
+	return &PlaylistPage{
+		Playlists: playlists,
+		Total:     int(page.Total),
+		Limit:     int(page.Limit),
+		Offset:    int(page.Offset),
+	}, nil
+}
+
+func newPlaylist(playlist spotify.SimplePlaylist) Playlist {
+	owner := playlist.Owner.DisplayName
+	if owner == "" {
+		owner = playlist.Owner.ID
+	}
+
+	return Playlist{
+		ID:          string(playlist.ID),
+		Name:        playlist.Name,
+		Description: playlist.Description,
+		Owner:       owner,
+		TrackCount:  int(playlist.Tracks.Total),
+		URI:         string(playlist.URI),
+		SnapshotID:  playlist.SnapshotID,
+	}
+}
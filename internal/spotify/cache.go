@@ -0,0 +1,531 @@
+package spotify
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+)
+
+const defaultCacheSizeMB = 64
+
+// defaultCacheTTLs are per-endpoint lifetimes, keyed by the first path
+// segment of the Web API URL. Endpoints not listed are not cached.
+var defaultCacheTTLs = map[string]time.Duration{
+	"tracks":    24 * time.Hour,
+	"artists":   24 * time.Hour,
+	"albums":    24 * time.Hour,
+	"playlists": 10 * time.Minute,
+	"search":    time.Hour,
+	"browse":    6 * time.Hour,
+	"users":     time.Hour,
+	"me":        5 * time.Minute,
+}
+
+// cacheEntry is a stored GET response. Entries outlive their TTL so they
+// can be revalidated with If-None-Match.
+type cacheEntry struct {
+	Body      []byte      `json:"body"`
+	Header    http.Header `json:"header"`
+	ETag      string      `json:"etag,omitempty"`
+	StoredAt  time.Time   `json:"stored_at"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+func (e *cacheEntry) size() int64 {
+	return int64(len(e.Body))
+}
+
+// cacheStore is a size-bounded LRU of cache entries.
+type cacheStore interface {
+	Get(key string) (*cacheEntry, bool)
+	Set(key string, entry *cacheEntry)
+}
+
+// CacheStats reports cache effectiveness per endpoint.
+type CacheStats struct {
+	Backend   string                    `json:"backend"`
+	Endpoints map[string]*EndpointStats `json:"endpoints"`
+}
+
+type EndpointStats struct {
+	Hits        int64 `json:"hits"`
+	Misses      int64 `json:"misses"`
+	Revalidated int64 `json:"revalidated"`
+	Stale       int64 `json:"stale"`
+}
+
+// cacheTransport serves GET responses from a cache, revalidating expired
+// entries with their ETag. Responses for the current user, including those
+// using market=from_token, are keyed by userScope.
+type cacheTransport struct {
+	next      http.RoundTripper
+	store     cacheStore
+	backend   string
+	ttls      map[string]time.Duration
+	userScope string
+
+	// writtenAt records when each resource, e.g. playlists/{id}, was last
+	// changed through this client, so entries stored before then are
+	// revalidated rather than served.
+	writtenMu sync.Mutex
+	writtenAt map[string]time.Time
+
+	statsMu sync.Mutex
+	stats   map[string]*EndpointStats
+}
+
+type revalidateContextKey struct{}
+
+// withRevalidation makes requests revalidate cached entries that are still
+// fresh, for reads that must reflect the latest state.
+func withRevalidation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, revalidateContextKey{}, true)
+}
+
+func newCacheTransport(next http.RoundTripper, cfg config.CacheConfig, userScope string) (*cacheTransport, error) {
+	maxBytes := int64(cfg.MaxSizeMB) << 20
+	if maxBytes <= 0 {
+		maxBytes = defaultCacheSizeMB << 20
+	}
+
+	ttls := make(map[string]time.Duration, len(defaultCacheTTLs))
+	for endpoint, ttl := range defaultCacheTTLs {
+		ttls[endpoint] = ttl
+	}
+	for endpoint, seconds := range cfg.TTLs {
+		ttls[endpoint] = time.Duration(seconds) * time.Second
+	}
+
+	var store cacheStore
+	backend := cfg.Backend
+	switch backend {
+	case "", "memory":
+		backend = "memory"
+		store = newMemoryStore(maxBytes)
+	case "disk":
+		var err error
+		if store, err = newDiskStore(cfg.Dir, maxBytes); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", cfg.Backend)
+	}
+
+	return &cacheTransport{
+		next:      next,
+		store:     store,
+		backend:   backend,
+		ttls:      ttls,
+		userScope: userScope,
+		writtenAt: make(map[string]time.Time),
+		stats:     make(map[string]*EndpointStats),
+	}, nil
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := endpointName(req.URL.Path)
+	ttl := t.ttls[endpoint]
+	if req.Method != http.MethodGet {
+		resp, err := t.next.RoundTrip(req)
+		if err == nil && resp.StatusCode < 300 {
+			t.markWritten(req.URL.Path)
+		}
+		return resp, err
+	}
+	if ttl <= 0 {
+		return t.next.RoundTrip(req)
+	}
+
+	key := t.cacheKey(req, endpoint)
+	entry, found := t.store.Get(key)
+	if found && time.Now().Before(entry.ExpiresAt) && !t.mustRevalidate(req, entry) {
+		t.record(endpoint, func(s *EndpointStats) { s.Hits++ })
+		return entry.response(req, "HIT"), nil
+	}
+
+	if found && entry.ETag != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if found && isServeStale(req, resp, err) {
+		// Degraded mode: an expired answer beats no answer
+		if resp != nil {
+			drain(resp)
+		}
+		t.record(endpoint, func(s *EndpointStats) { s.Stale++ })
+		markStale(req.Context(), entry.StoredAt)
+		return entry.response(req, "STALE"), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if found && resp.StatusCode == http.StatusNotModified {
+		drain(resp)
+		t.record(endpoint, func(s *EndpointStats) { s.Revalidated++ })
+		revalidated := *entry
+		revalidated.ExpiresAt = time.Now().Add(ttl)
+		t.store.Set(key, &revalidated)
+		return revalidated.response(req, "REVALIDATED"), nil
+	}
+
+	t.record(endpoint, func(s *EndpointStats) { s.Misses++ })
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	now := time.Now()
+	t.store.Set(key, &cacheEntry{
+		Body:      body,
+		Header:    http.Header{"Content-Type": resp.Header.Values("Content-Type")},
+		ETag:      resp.Header.Get("ETag"),
+		StoredAt:  now,
+		ExpiresAt: now.Add(ttl),
+	})
+	return resp, nil
+}
+
+// markWritten records a successful change to the resource at path.
+func (t *cacheTransport) markWritten(path string) {
+	t.writtenMu.Lock()
+	defer t.writtenMu.Unlock()
+
+	now := time.Now()
+	t.writtenAt[resourceName(path)] = now
+	// Entries stored before the longest TTL have expired anyway
+	var longest time.Duration
+	for _, ttl := range t.ttls {
+		longest = max(longest, ttl)
+	}
+	for resource, at := range t.writtenAt {
+		if now.Sub(at) > longest {
+			delete(t.writtenAt, resource)
+		}
+	}
+}
+
+// mustRevalidate reports whether a fresh entry may be out of date: the
+// request asked for revalidation, or the resource was changed after the
+// entry was stored.
+func (t *cacheTransport) mustRevalidate(req *http.Request, entry *cacheEntry) bool {
+	if revalidate, _ := req.Context().Value(revalidateContextKey{}).(bool); revalidate {
+		return true
+	}
+
+	t.writtenMu.Lock()
+	defer t.writtenMu.Unlock()
+	written, ok := t.writtenAt[resourceName(req.URL.Path)]
+	return ok && !entry.StoredAt.After(written)
+}
+
+// Stats returns a snapshot of hit/miss counters.
+func (t *cacheTransport) Stats() CacheStats {
+	t.statsMu.Lock()
+	defer t.statsMu.Unlock()
+
+	stats := CacheStats{
+		Backend:   t.backend,
+		Endpoints: make(map[string]*EndpointStats, len(t.stats)),
+	}
+	for endpoint, s := range t.stats {
+		snapshot := *s
+		stats.Endpoints[endpoint] = &snapshot
+	}
+	return stats
+}
+
+func (t *cacheTransport) record(endpoint string, update func(*EndpointStats)) {
+	t.statsMu.Lock()
+	defer t.statsMu.Unlock()
+
+	s, ok := t.stats[endpoint]
+	if !ok {
+		s = &EndpointStats{}
+		t.stats[endpoint] = s
+	}
+	update(s)
+}
+
+func (t *cacheTransport) cacheKey(req *http.Request, endpoint string) string {
+	query := req.URL.Query()
+	scope := "app"
+	if endpoint == "me" || strings.EqualFold(query.Get("market"), marketFromToken) {
+		scope = t.userScope
+	}
+	// Encode sorts parameters, so equivalent queries share an entry
+	return scope + " " + req.URL.Path + "?" + query.Encode() + " " + req.Header.Get("Accept-Language")
+}
+
+// isServeStale reports whether an expired entry should stand in for a
+// failed upstream request the caller is still waiting on.
+func isServeStale(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	return err != nil || resp.StatusCode >= 500
+}
+
+func (e *cacheEntry) response(req *http.Request, status string) *http.Response {
+	header := e.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("X-Cache", status)
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// endpointName returns the first path segment after the API version, e.g.
+// "tracks" for /v1/tracks/{id}, also when api_base_url adds a path prefix.
+func endpointName(path string) string {
+	if i := strings.Index(path, "/v1/"); i >= 0 {
+		path = path[i+len("/v1/"):]
+	}
+	path = strings.TrimPrefix(path, "/")
+	// Playback state changes from moment to moment, so it is kept apart
+	// from the rest of /me and never cached
+	if strings.HasPrefix(path, "me/player") {
+		return "me/player"
+	}
+	if i := strings.Index(path, "/"); i >= 0 {
+		path = path[:i]
+	}
+	return path
+}
+
+// resourceName returns the first two path segments after the API version,
+// e.g. "playlists/{id}" for both /v1/playlists/{id} and its /tracks, so a
+// write to a resource is seen by every read of it.
+func resourceName(path string) string {
+	if i := strings.Index(path, "/v1/"); i >= 0 {
+		path = path[i+len("/v1/"):]
+	}
+	segments := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 3)
+	return strings.Join(segments[:min(len(segments), 2)], "/")
+}
+
+// userScopeKey derives a stable cache scope from a user credential without
+// storing the credential itself.
+func userScopeKey(credential string) string {
+	sum := sha256.Sum256([]byte(credential))
+	return "user:" + hex.EncodeToString(sum[:8])
+}
+
+// lruIndex tracks keys in recency order and evicts the least recently used
+// ones once the total size exceeds maxBytes.
+type lruIndex struct {
+	maxBytes int64
+	size     int64
+	order    *list.List
+	items    map[string]*list.Element
+	onEvict  func(key string, value interface{})
+}
+
+type lruItem struct {
+	key   string
+	size  int64
+	value interface{}
+}
+
+func newLRUIndex(maxBytes int64, onEvict func(key string, value interface{})) *lruIndex {
+	return &lruIndex{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+		onEvict:  onEvict,
+	}
+}
+
+func (l *lruIndex) get(key string) (interface{}, bool) {
+	elem, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(elem)
+	return elem.Value.(*lruItem).value, true
+}
+
+func (l *lruIndex) add(key string, size int64, value interface{}) {
+	if elem, ok := l.items[key]; ok {
+		item := elem.Value.(*lruItem)
+		l.size += size - item.size
+		item.size = size
+		item.value = value
+		l.order.MoveToFront(elem)
+	} else {
+		l.items[key] = l.order.PushFront(&lruItem{key: key, size: size, value: value})
+		l.size += size
+	}
+
+	for l.size > l.maxBytes && l.order.Len() > 1 {
+		oldest := l.order.Back()
+		item := oldest.Value.(*lruItem)
+		l.order.Remove(oldest)
+		delete(l.items, item.key)
+		l.size -= item.size
+		if l.onEvict != nil {
+			l.onEvict(item.key, item.value)
+		}
+	}
+}
+
+type memoryStore struct {
+	mu    sync.Mutex
+	index *lruIndex
+}
+
+func newMemoryStore(maxBytes int64) *memoryStore {
+	return &memoryStore{index: newLRUIndex(maxBytes, nil)}
+}
+
+func (s *memoryStore) Get(key string) (*cacheEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.index.get(key)
+	if !ok {
+		return nil, false
+	}
+	return value.(*cacheEntry), true
+}
+
+func (s *memoryStore) Set(key string, entry *cacheEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.index.add(key, entry.size(), entry)
+}
+
+// diskStore keeps one JSON file per entry under dir and an in-memory LRU
+// index of their sizes. Existing files are picked up on startup.
+type diskStore struct {
+	mu    sync.Mutex
+	dir   string
+	index *lruIndex
+}
+
+func newDiskStore(dir string, maxBytes int64) (*diskStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("cache dir is required for the disk backend")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %w", err)
+	}
+
+	s := &diskStore{dir: dir}
+	s.index = newLRUIndex(maxBytes, func(key string, _ interface{}) {
+		os.Remove(s.path(key))
+	})
+
+	// Rebuild the index oldest first so recency is roughly preserved
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	type existing struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+	var found []existing
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		found = append(found, existing{
+			name:    strings.TrimSuffix(filepath.Base(file), ".json"),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].modTime.Before(found[j].modTime) })
+	for _, f := range found {
+		s.index.add(f.name, f.size, nil)
+	}
+
+	return s, nil
+}
+
+func (s *diskStore) Get(key string) (*cacheEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := hashKey(key)
+	if _, ok := s.index.get(name); !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(s.path(name))
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (s *diskStore) Set(key string, entry *cacheEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	name := hashKey(key)
+	tmp := s.path(name) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, s.path(name)); err != nil {
+		os.Remove(tmp)
+		return
+	}
+	s.index.add(name, int64(len(data)), nil)
+}
+
+func (s *diskStore) path(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
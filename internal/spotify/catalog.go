@@ -0,0 +1,129 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+func (c *Client) GetArtist(ctx context.Context, artistID string) (*Artist, error) {
+	return coalesce(ctx, &c.flight, flightKey("GetArtist", artistID), func(ctx context.Context) (*Artist, error) {
+		return c.getArtist(ctx, artistID)
+	})
+}
+
+func (c *Client) getArtist(ctx context.Context, artistID string) (*Artist, error) {
+	artist, err := c.client.GetArtist(ctx, spotify.ID(artistID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artist: %w", err)
+	}
+
+	return &Artist{
+		ID:         string(artist.ID),
+		Name:       artist.Name,
+		Popularity: int(artist.Popularity),
+		URI:        string(artist.URI),
+		Genres:     artist.Genres,
+		Followers:  int(artist.Followers.Count),
+	}, nil
+}
+
+func (c *Client) GetAlbum(ctx context.Context, albumID string, market string) (*Album, error) {
+	market, err := c.resolveMarket(market)
+	if err != nil {
+		return nil, err
+	}
+
+	key := flightKey("GetAlbum", albumID, market)
+	return coalesce(ctx, &c.flight, key, func(ctx context.Context) (*Album, error) {
+		return c.getAlbum(ctx, albumID, market)
+	})
+}
+
+func (c *Client) getAlbum(ctx context.Context, albumID string, market string) (*Album, error) {
+	params := url.Values{}
+	if market != "" {
+		params.Set("market", market)
+	}
+
+	// Decoded by hand so album tracks keep their playability fields
+	var album struct {
+		spotify.SimpleAlbum
+		Tracks struct {
+			Items []trackObject `json:"items"`
+		} `json:"tracks"`
+	}
+	if err := c.getJSON(ctx, "albums/"+url.PathEscape(albumID), params, &album); err != nil {
+		return nil, fmt.Errorf("failed to get album: %w", err)
+	}
+
+	artistName := "Unknown Artist"
+	if len(album.Artists) > 0 {
+		artistName = album.Artists[0].Name
+	}
+
+	// Album tracks are returned without their album
+	tracks := make([]Track, len(album.Tracks.Items))
+	for i, track := range album.Tracks.Items {
+		tracks[i] = newTrack(track)
+		tracks[i].Album = album.Name
+	}
+
+	return &Album{
+		ID:          string(album.ID),
+		Name:        album.Name,
+		Artist:      artistName,
+		ReleaseDate: album.ReleaseDate,
+		TotalTracks: int(album.TotalTracks),
+		Tracks:      tracks,
+		URI:         string(album.URI),
+	}, nil
+}
+
+// GetPlaylist returns a playlist with the first page of its tracks.
+func (c *Client) GetPlaylist(ctx context.Context, playlistID string, market string) (*Playlist, error) {
+	market, err := c.resolveMarket(market)
+	if err != nil {
+		return nil, err
+	}
+
+	key := flightKey("GetPlaylist", playlistID, market)
+	return coalesce(ctx, &c.flight, key, func(ctx context.Context) (*Playlist, error) {
+		return c.getPlaylist(ctx, playlistID, market)
+	})
+}
+
+func (c *Client) getPlaylist(ctx context.Context, playlistID string, market string) (*Playlist, error) {
+	params := url.Values{}
+	if market != "" {
+		params.Set("market", market)
+	}
+
+	var playlist struct {
+		spotify.SimplePlaylist
+		Tracks struct {
+			Items []struct {
+				Track *trackObject `json:"track"`
+			} `json:"items"`
+			Total spotify.Numeric `json:"total"`
+		} `json:"tracks"`
+	}
+	if err := c.getJSON(ctx, "playlists/"+url.PathEscape(playlistID), params, &playlist); err != nil {
+		return nil, fmt.Errorf("failed to get playlist: %w", err)
+	}
+
+	result := newPlaylist(playlist.SimplePlaylist)
+	result.TrackCount = int(playlist.Tracks.Total)
+	result.Tracks = []Track{}
+	for _, item := range playlist.Tracks.Items {
+		// Skip removed tracks and podcast episodes
+		if item.Track == nil || item.Track.ID == "" || item.Track.Type == "episode" {
+			continue
+		}
+		result.Tracks = append(result.Tracks, newTrack(*item.Track))
+	}
+
+	return &result, nil
+}
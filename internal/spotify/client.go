@@ -1,77 +1,227 @@
 package spotify
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/anuragkothare/spotify_mcp_server/internal/config"
 	"github.com/zmb3/spotify/v2"
 	spotifyauth "github.com/zmb3/spotify/v2/auth"
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 )
 
+const (
+	// marketFromToken asks Spotify to use the market of the user the access
+	// token belongs to.
+	marketFromToken = "from_token"
+)
+
 type Client struct {
-	client *spotify.Client
-	auth   *spotifyauth.Authenticator
+	client     *spotify.Client
+	auth       *oauth2.Config
+	httpClient *http.Client
+	apiBaseURL string
+	cache      *cacheTransport
+	breaker    *breakerTransport
+
+	// userAuthorized is set when requests are made with a user token
+	// rather than app-only client credentials.
+	userAuthorized bool
+	defaultMarket  string
+
+	userMu      sync.Mutex
+	currentUser *CurrentUser
+
+	// flight coalesces concurrent identical requests; batcher, when
+	// enabled, merges concurrent GetTrack calls into multi-ID requests.
+	flight  flightGroup
+	batcher *trackBatcher
 }
 
 func NewClient(cfg config.SpotifyConfig) (*Client, error) {
-	auth := spotifyauth.New(
-		spotifyauth.WithClientID(cfg.ClientID),
-		spotifyauth.WithClientSecret(cfg.ClientSecret),
-		spotifyauth.WithRedirectURL(cfg.RedirectURI),
-		spotifyauth.WithScopes(
+	base, err := newBaseTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewClientWithTransport(cfg, base)
+}
+
+// NewClientWithTransport is like NewClient but sends token and API requests
+// through base, e.g. to reach a spotifytest stand-in. The proxy and CA
+// settings are not applied to base.
+func NewClientWithTransport(cfg config.SpotifyConfig, base http.RoundTripper) (*Client, error) {
+	// Recording sits beneath OAuth so token requests are captured and
+	// replayed too
+	base, err := withRecording(base, cfg.Recording)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up recording: %w", err)
+	}
+
+	// oauth2 uses the client in the context both to fetch tokens and as the
+	// transport beneath its own
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: base})
+
+	endpoints, err := newEndpoints(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// spotifyauth.Authenticator fixes the token URL, so the OAuth config is
+	// built directly to honour accounts_base_url
+	auth := &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURI,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  endpoints.authURL(),
+			TokenURL: endpoints.tokenURL(),
+		},
+		Scopes: []string{
 			spotifyauth.ScopeUserReadPrivate,
 			spotifyauth.ScopeUserReadEmail,
 			spotifyauth.ScopePlaylistReadPrivate,
 			spotifyauth.ScopePlaylistReadCollaborative,
+			spotifyauth.ScopePlaylistModifyPublic,
+			spotifyauth.ScopePlaylistModifyPrivate,
 			spotifyauth.ScopeUserLibraryRead,
 			spotifyauth.ScopeUserTopRead,
-		),
-	)
+			spotifyauth.ScopeUserReadPlaybackState,
+		},
+	}
 
-	// Use client credentials flow for app-only access
-	config := &clientcredentials.Config{
-		ClientID:     cfg.ClientID,
-		ClientSecret: cfg.ClientSecret,
-		TokenURL:     spotifyauth.TokenURL,
+	var httpClient *http.Client
+	userAuthorized := cfg.RefreshToken != ""
+	if userAuthorized {
+		// Act on behalf of the user who granted the refresh token
+		token, err := auth.TokenSource(ctx, &oauth2.Token{RefreshToken: cfg.RefreshToken}).Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to refresh user token: %w", err)
+		}
+		httpClient = auth.Client(ctx, token)
+	} else {
+		// Use client credentials flow for app-only access
+		config := &clientcredentials.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			TokenURL:     endpoints.tokenURL(),
+		}
+
+		token, err := config.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token: %w", err)
+		}
+		httpClient = oauth2.NewClient(ctx, oauth2.ReuseTokenSource(token, config.TokenSource(ctx)))
 	}
 
-	token, err := config.Token(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("failed to get token: %w", err)
+	httpClient.Transport = newRetryTransport(httpClient.Transport, cfg.MaxRetries, time.Duration(cfg.MaxRetryWait)*time.Second)
+	breaker := newBreakerTransport(httpClient.Transport, cfg.Breaker)
+	httpClient.Transport = breaker
+
+	var cache *cacheTransport
+	if cfg.Cache.Enabled {
+		cache, err = newCacheTransport(httpClient.Transport, cfg.Cache, userScopeKey(cfg.RefreshToken))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cache: %w", err)
+		}
+		httpClient.Transport = cache
 	}
 
-	httpClient := spotifyauth.New().Client(context.Background(), token)
-	client := spotify.New(httpClient)
+	c := &Client{
+		client:         spotify.New(httpClient, spotify.WithBaseURL(endpoints.api)),
+		auth:           auth,
+		httpClient:     httpClient,
+		apiBaseURL:     endpoints.api,
+		cache:          cache,
+		breaker:        breaker,
+		userAuthorized: userAuthorized,
+		defaultMarket:  cfg.DefaultMarket,
+	}
+	if cfg.BatchWindowMs > 0 {
+		c.batcher = newTrackBatcher(c, time.Duration(cfg.BatchWindowMs)*time.Millisecond)
+	}
 
-	return &Client{
-		client: client,
-		auth:   auth,
-	}, nil
+	return c, nil
 }
 
-func (c *Client) SearchTracks(query string, limit int) (*SearchResult, error) {
-	results, err := c.client.Search(context.Background(), query, spotify.SearchTypeTrack, spotify.Limit(limit))
-	if err != nil {
-		return nil, fmt.Errorf("failed to search tracks: %w", err)
+// CacheStats reports cache hits and misses per endpoint, or nil when the
+// cache is disabled.
+func (c *Client) CacheStats() *CacheStats {
+	if c.cache == nil {
+		return nil
 	}
+	stats := c.cache.Stats()
+	return &stats
+}
 
-	tracks := make([]Track, len(results.Tracks.Tracks))
-	for i, track := range results.Tracks.Tracks {
-		// Handle case where track might not have artists
-		artistName := "Unknown Artist"
-		if len(track.Artists) > 0 {
-			artistName = track.Artists[0].Name
-		}
+// BreakerStatus reports the state of the circuit breaker guarding Spotify.
+func (c *Client) BreakerStatus() BreakerStatus {
+	return c.breaker.Status()
+}
 
-		tracks[i] = Track{
-			ID:     string(track.ID),
-			Name:   track.Name,
-			Artist: artistName,
-			Album:  track.Album.Name,
-			URI:    string(track.URI),
+// resolveMarket picks the market for catalog requests: the caller's choice,
+// then the configured default, then the user's own market when a user token
+// is available.
+func (c *Client) resolveMarket(market string) (string, error) {
+	if market == "" {
+		market = c.defaultMarket
+	}
+	if market == "" && c.userAuthorized {
+		market = marketFromToken
+	}
+	if strings.EqualFold(market, marketFromToken) {
+		if !c.userAuthorized {
+			return "", fmt.Errorf("market %q: %w", marketFromToken, ErrUserAuthRequired)
 		}
+		return marketFromToken, nil
+	}
+	return strings.ToUpper(market), nil
+}
+
+func (c *Client) SearchTracks(ctx context.Context, query string, limit int, market string) (*SearchResult, error) {
+	market, err := c.resolveMarket(market)
+	if err != nil {
+		return nil, err
+	}
+
+	key := flightKey("SearchTracks", normalizeQuery(query), limit, market)
+	return coalesce(ctx, &c.flight, key, func(ctx context.Context) (*SearchResult, error) {
+		return c.searchTracks(ctx, query, limit, market)
+	})
+}
+
+func (c *Client) searchTracks(ctx context.Context, query string, limit int, market string) (*SearchResult, error) {
+	params := url.Values{
+		"q":     {query},
+		"type":  {"track"},
+		"limit": {strconv.Itoa(limit)},
+	}
+	if market != "" {
+		params.Set("market", market)
+	}
+
+	var results struct {
+		Tracks struct {
+			Items []trackObject   `json:"items"`
+			Total spotify.Numeric `json:"total"`
+		} `json:"tracks"`
+	}
+	if err := c.getJSON(ctx, "search", params, &results); err != nil {
+		return nil, fmt.Errorf("failed to search tracks: %w", err)
+	}
+
+	tracks := make([]Track, len(results.Tracks.Items))
+	for i, track := range results.Tracks.Items {
+		tracks[i] = newTrack(track)
 	}
 
 	return &SearchResult{
@@ -80,8 +230,25 @@ func (c *Client) SearchTracks(query string, limit int) (*SearchResult, error) {
 	}, nil
 }
 
-func (c *Client) SearchArtists(query string, limit int) (*ArtistSearchResult, error) {
-	results, err := c.client.Search(context.Background(), query, spotify.SearchTypeArtist, spotify.Limit(limit))
+func (c *Client) SearchArtists(ctx context.Context, query string, limit int, market string) (*ArtistSearchResult, error) {
+	market, err := c.resolveMarket(market)
+	if err != nil {
+		return nil, err
+	}
+
+	key := flightKey("SearchArtists", normalizeQuery(query), limit, market)
+	return coalesce(ctx, &c.flight, key, func(ctx context.Context) (*ArtistSearchResult, error) {
+		return c.searchArtists(ctx, query, limit, market)
+	})
+}
+
+func (c *Client) searchArtists(ctx context.Context, query string, limit int, market string) (*ArtistSearchResult, error) {
+	opts := []spotify.RequestOption{spotify.Limit(limit)}
+	if market != "" {
+		opts = append(opts, spotify.Market(market))
+	}
+
+	results, err := c.client.Search(ctx, query, spotify.SearchTypeArtist, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search artists: %w", err)
 	}
@@ -102,23 +269,128 @@ func (c *Client) SearchArtists(query string, limit int) (*ArtistSearchResult, er
 	}, nil
 }
 
-func (c *Client) GetTrack(trackID string) (*Track, error) {
-	track, err := c.client.GetTrack(context.Background(), spotify.ID(trackID))
+func (c *Client) GetTrack(ctx context.Context, trackID string, market string) (*Track, error) {
+	market, err := c.resolveMarket(market)
 	if err != nil {
+		return nil, err
+	}
+
+	key := flightKey("GetTrack", trackID, market)
+	return coalesce(ctx, &c.flight, key, func(ctx context.Context) (*Track, error) {
+		if c.batcher != nil {
+			return c.batcher.get(ctx, trackID, market)
+		}
+		return c.getTrack(ctx, trackID, market)
+	})
+}
+
+func (c *Client) getTrack(ctx context.Context, trackID string, market string) (*Track, error) {
+	params := url.Values{}
+	if market != "" {
+		params.Set("market", market)
+	}
+
+	var track trackObject
+	if err := c.getJSON(ctx, "tracks/"+url.PathEscape(trackID), params, &track); err != nil {
 		return nil, fmt.Errorf("failed to get track: %w", err)
 	}
 
+	result := newTrack(track)
+	return &result, nil
+}
+
+// trackObject extends the library's FullTrack with the restriction Spotify
+// reports for unplayable tracks when a market is given.
+type trackObject struct {
+	spotify.FullTrack
+	Restrictions *struct {
+		Reason string `json:"reason"`
+	} `json:"restrictions"`
+}
+
+func newTrack(track trackObject) Track {
 	// Handle case where track might not have artists
 	artistName := "Unknown Artist"
 	if len(track.Artists) > 0 {
 		artistName = track.Artists[0].Name
 	}
 
-	return &Track{
-		ID:     string(track.ID),
-		Name:   track.Name,
-		Artist: artistName,
-		Album:  track.Album.Name,
-		URI:    string(track.URI),
-	}, nil
+	result := Track{
+		ID:         string(track.ID),
+		Name:       track.Name,
+		Artist:     artistName,
+		Album:      track.Album.Name,
+		URI:        string(track.URI),
+		IsPlayable: track.IsPlayable,
+	}
+
+	// With track relinking the returned track may differ from the one
+	// requested; keep the original ID so callers can match them up.
+	if track.LinkedFrom != nil && track.LinkedFrom.ID != track.ID {
+		result.LinkedFrom = string(track.LinkedFrom.ID)
+	}
+	if track.Restrictions != nil {
+		result.Restriction = track.Restrictions.Reason
+	}
+
+	return result
+}
+
+// getJSON performs a GET against the Web API for endpoints whose responses
+// the library does not fully decode.
+func (c *Client) getJSON(ctx context.Context, path string, params url.Values, result interface{}) error {
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	return c.sendJSON(ctx, http.MethodGet, path, nil, result)
+}
+
+// sendJSON performs a request against the Web API with body, if any, sent
+// as JSON, and decodes the response into result unless it is nil. Under a
+// dry run, requests other than GETs are only recorded; see WithDryRun.
+func (c *Client) sendJSON(ctx context.Context, method, path string, body, result interface{}) error {
+	if method != http.MethodGet {
+		if planned, err := PlanCall(ctx, method, c.apiBaseURL+path, body); planned {
+			return err
+		}
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.apiBaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		var e struct {
+			Error spotify.Error `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Error.Message == "" {
+			return fmt.Errorf("spotify: HTTP %d: %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+		}
+		e.Error.Status = resp.StatusCode
+		return e.Error
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
 }
@@ -0,0 +1,213 @@
+package spotify_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+	"github.com/anuragkothare/spotify_mcp_server/internal/spotify"
+	"github.com/anuragkothare/spotify_mcp_server/internal/spotify/spotifytest"
+	zspotify "github.com/zmb3/spotify/v2"
+)
+
+const (
+	roadTripID = "3cEYpjA9oz9GiPac4AsH4n"
+	trackA     = "4u7EnebtmKWzUH433cf5Qv"
+	trackB     = "4uLU6hMCjMI75M1A2tKUQC"
+	trackC     = "1lCRw5FEZ1gPDNPzy1K4zW"
+)
+
+// recorder notes the Web API requests that reach the stand-in, with their
+// response status. Requests for gatePath wait for gate to close once they
+// have been announced on arrived.
+type recorder struct {
+	next     http.RoundTripper
+	gatePath string
+	gate     chan struct{}
+	arrived  chan struct{}
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.gatePath != "" && req.URL.Path == r.gatePath {
+		r.arrived <- struct{}{}
+		<-r.gate
+	}
+	resp, err := r.next.RoundTrip(req)
+	if err == nil && strings.HasPrefix(req.URL.Path, "/v1/") {
+		r.mu.Lock()
+		r.requests = append(r.requests, req.Method+" "+req.URL.Path+"?"+req.URL.RawQuery+" "+resp.Status)
+		r.mu.Unlock()
+	}
+	return resp, err
+}
+
+// made returns the recorded requests whose method and path start with
+// prefix, e.g. "GET /v1/tracks".
+func (r *recorder) made(prefix string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var requests []string
+	for _, req := range r.requests {
+		if strings.HasPrefix(req, prefix) {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+// newStandInClient returns a real client talking to the Web API stand-in
+// through rec, with the response cache on.
+func newStandInClient(t *testing.T, rec *recorder, batchWindowMs int) *spotify.Client {
+	t.Helper()
+	srv := spotifytest.NewServer()
+	t.Cleanup(srv.Close)
+	rec.next = srv.Transport()
+
+	client, err := spotify.NewClientWithTransport(config.SpotifyConfig{
+		ClientID:      "client",
+		ClientSecret:  "secret",
+		RefreshToken:  "refresh",
+		Cache:         config.CacheConfig{Enabled: true},
+		BatchWindowMs: batchWindowMs,
+	}, rec)
+	if err != nil {
+		t.Fatalf("NewClientWithTransport: %v", err)
+	}
+	return client
+}
+
+func TestClientRevalidatesWithETag(t *testing.T) {
+	rec := &recorder{}
+	client := newStandInClient(t, rec, 0)
+	ctx := context.Background()
+
+	// Playlist items are always revalidated, since edits are based on them
+	for range 2 {
+		if _, err := client.GetPlaylistItems(ctx, roadTripID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	requests := rec.made("GET /v1/playlists/" + roadTripID + "?")
+	if len(requests) != 2 || !strings.HasSuffix(requests[1], "304 Not Modified") {
+		t.Fatalf("got requests %v, want a fetch and a 304 revalidation", requests)
+	}
+	if stats := client.CacheStats().Endpoints["playlists"]; stats == nil || stats.Revalidated != 1 {
+		t.Errorf("got playlist cache stats %+v, want one revalidation", stats)
+	}
+
+	// A change made through the client is picked up
+	if _, err := client.AddPlaylistItems(ctx, roadTripID, []string{"spotify:track:" + trackC}, nil); err != nil {
+		t.Fatal(err)
+	}
+	items, err := client.GetPlaylistItems(ctx, roadTripID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items.Items) != 3 {
+		t.Errorf("got %d items after adding one, want 3", len(items.Items))
+	}
+	requests = rec.made("GET /v1/playlists/" + roadTripID + "?")
+	if last := requests[len(requests)-1]; !strings.HasSuffix(last, "200 OK") {
+		t.Errorf("got %s after the edit, want a fresh 200", last)
+	}
+}
+
+func TestClientCoalescesSearches(t *testing.T) {
+	rec := &recorder{
+		gatePath: "/v1/search",
+		gate:     make(chan struct{}),
+		arrived:  make(chan struct{}, 1),
+	}
+	client := newStandInClient(t, rec, 0)
+
+	// Queries that differ only in case and spacing share one request
+	queries := []string{"Queen", "queen", "  QUEEN "}
+	errs := make(chan error, len(queries))
+	for _, query := range queries {
+		go func() {
+			_, err := client.SearchTracks(context.Background(), query, 5, "")
+			errs <- err
+		}()
+	}
+	<-rec.arrived
+	// Give the other callers time to join the request in flight
+	time.Sleep(50 * time.Millisecond)
+	close(rec.gate)
+
+	for range queries {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if requests := rec.made("GET /v1/search"); len(requests) != 1 {
+		t.Errorf("got requests %v, want one search", requests)
+	}
+}
+
+func TestClientBatchesTracks(t *testing.T) {
+	rec := &recorder{}
+	client := newStandInClient(t, rec, 50)
+
+	ids := []string{trackA, trackB}
+	tracks := make([]*spotify.Track, len(ids))
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tracks[i], errs[i] = client.GetTrack(context.Background(), id, "US")
+		}()
+	}
+	wg.Wait()
+
+	for i, id := range ids {
+		if errs[i] != nil {
+			t.Fatalf("GetTrack %s: %v", id, errs[i])
+		}
+		if tracks[i].ID != id {
+			t.Errorf("got track %s for %s", tracks[i].ID, id)
+		}
+	}
+	requests := rec.made("GET /v1/tracks")
+	if len(requests) != 1 || !strings.Contains(requests[0], "ids=") {
+		t.Errorf("got requests %v, want one multi-ID request", requests)
+	}
+}
+
+func TestClientBatchedTrackNotFound(t *testing.T) {
+	rec := &recorder{}
+	client := newStandInClient(t, rec, 50)
+
+	ids := []string{trackA, "0000000000000000000000"}
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = client.GetTrack(context.Background(), id, "")
+		}()
+	}
+	wg.Wait()
+
+	if errs[0] != nil {
+		t.Fatalf("GetTrack %s: %v", trackA, errs[0])
+	}
+	// An unknown ID fails the same way whether or not it was batched
+	var spotifyErr zspotify.Error
+	if !errors.As(errs[1], &spotifyErr) || spotifyErr.Status != http.StatusNotFound {
+		t.Errorf("got %v for an unknown track, want a 404", errs[1])
+	}
+	if requests := rec.made("GET /v1/tracks"); len(requests) != 1 {
+		t.Errorf("got requests %v, want one multi-ID request", requests)
+	}
+}
@@ -0,0 +1,254 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+const (
+	maxBatchSize        = 50 // Spotify's limit for GET /tracks?ids=
+	batchRequestTimeout = 20 * time.Second
+)
+
+// flightGroup coalesces concurrent calls with the same key into a single
+// upstream call. Unlike a plain single-flight group, the shared call is
+// only cancelled once every caller waiting on it has given up, so one
+// caller's cancellation does not fail the others.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done    chan struct{}
+	val     interface{}
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+	stale   *Staleness
+}
+
+func (g *flightGroup) do(ctx context.Context, key string, fn func(context.Context) (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	call, ok := g.calls[key]
+	if ok {
+		call.waiters++
+	} else {
+		// The shared call outlives any single caller but keeps the
+		// first caller's deadline.
+		var callCtx context.Context
+		var cancel context.CancelFunc
+		if deadline, ok := ctx.Deadline(); ok {
+			callCtx, cancel = context.WithDeadline(context.WithoutCancel(ctx), deadline)
+		} else {
+			callCtx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+		}
+		callCtx, stale := WithStalenessReport(callCtx)
+		call = &flightCall{done: make(chan struct{}), waiters: 1, cancel: cancel, stale: stale}
+		g.calls[key] = call
+
+		go func() {
+			defer cancel()
+			call.val, call.err = fn(callCtx)
+
+			g.mu.Lock()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+			g.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		copyStaleness(ctx, call.stale)
+		return call.val, call.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// Nobody is waiting any more; later callers start afresh
+			call.cancel()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// coalesce runs fn through the client's flight group under key.
+func coalesce[T any](ctx context.Context, g *flightGroup, key string, fn func(context.Context) (*T, error)) (*T, error) {
+	val, err := g.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return fn(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*T), nil
+}
+
+// flightKey builds a coalescing key from a method name and its normalized
+// arguments.
+func flightKey(method string, args ...interface{}) string {
+	var b strings.Builder
+	b.WriteString(method)
+	for _, arg := range args {
+		b.WriteString("|")
+		fmt.Fprint(&b, arg)
+	}
+	return b.String()
+}
+
+// normalizeQuery makes search queries that differ only in case or spacing
+// share an upstream call.
+func normalizeQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+// trackBatcher collects concurrent single-track lookups for a short window
+// and fetches them with one multi-ID request per market.
+type trackBatcher struct {
+	client  *Client
+	window  time.Duration
+	timeout time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*trackBatch // keyed by market
+}
+
+// trackBatch is one pending multi-ID request. Like a flightGroup call, it
+// carries the first caller's context values but is only cancelled once
+// every caller waiting on it has given up.
+type trackBatch struct {
+	ids     []string
+	waiters map[string][]chan trackResult
+	waiting int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	stale  *Staleness
+}
+
+type trackResult struct {
+	track *Track
+	err   error
+}
+
+func newTrackBatcher(client *Client, window time.Duration) *trackBatcher {
+	return &trackBatcher{
+		client:  client,
+		window:  window,
+		timeout: batchRequestTimeout,
+		pending: make(map[string]*trackBatch),
+	}
+}
+
+func (b *trackBatcher) get(ctx context.Context, trackID, market string) (*Track, error) {
+	result := make(chan trackResult, 1)
+
+	b.mu.Lock()
+	batch, ok := b.pending[market]
+	if !ok {
+		batchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.timeout)
+		batchCtx, stale := WithStalenessReport(batchCtx)
+		batch = &trackBatch{
+			waiters: make(map[string][]chan trackResult),
+			ctx:     batchCtx,
+			cancel:  cancel,
+			stale:   stale,
+		}
+		b.pending[market] = batch
+		time.AfterFunc(b.window, func() { b.flush(market, batch) })
+	}
+	if _, seen := batch.waiters[trackID]; !seen {
+		batch.ids = append(batch.ids, trackID)
+	}
+	batch.waiters[trackID] = append(batch.waiters[trackID], result)
+	batch.waiting++
+	if len(batch.ids) >= maxBatchSize {
+		// Full batches go out immediately; the timer finds them gone
+		delete(b.pending, market)
+		go b.fetch(market, batch)
+	}
+	b.mu.Unlock()
+
+	select {
+	case r := <-result:
+		copyStaleness(ctx, batch.stale)
+		return r.track, r.err
+	case <-ctx.Done():
+		b.mu.Lock()
+		batch.waiting--
+		if batch.waiting == 0 {
+			// Nobody is waiting any more; later callers start a new batch
+			batch.cancel()
+			if b.pending[market] == batch {
+				delete(b.pending, market)
+			}
+		}
+		b.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (b *trackBatcher) flush(market string, batch *trackBatch) {
+	b.mu.Lock()
+	if b.pending[market] != batch {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, market)
+	b.mu.Unlock()
+
+	b.fetch(market, batch)
+}
+
+func (b *trackBatcher) fetch(market string, batch *trackBatch) {
+	ctx := batch.ctx
+	defer batch.cancel()
+
+	params := url.Values{"ids": {strings.Join(batch.ids, ",")}}
+	if market != "" {
+		params.Set("market", market)
+	}
+
+	var results struct {
+		Tracks []*trackObject `json:"tracks"`
+	}
+	err := b.client.getJSON(ctx, "tracks", params, &results)
+
+	for i, id := range batch.ids {
+		var r trackResult
+		switch {
+		case err != nil:
+			r.err = fmt.Errorf("failed to get track: %w", err)
+		case i >= len(results.Tracks) || results.Tracks[i] == nil:
+			// Results are positional; unknown IDs come back as null. Report
+			// them as GET /tracks/{id} would, so callers see a 404.
+			r.err = fmt.Errorf("failed to get track: %w", spotify.Error{
+				Message: "track " + id + " not found",
+				Status:  http.StatusNotFound,
+			})
+		default:
+			track := newTrack(*results.Tracks[i])
+			r.track = &track
+		}
+		for _, waiter := range batch.waiters[id] {
+			waiter <- r
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// PlannedCall is a Web API request that a dry run recorded instead of
+// sending.
+type PlannedCall struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// Plan collects the calls made under a dry run.
+type Plan struct {
+	mu    sync.Mutex
+	calls []PlannedCall
+}
+
+// Calls returns the recorded calls in order.
+func (p *Plan) Calls() []PlannedCall {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PlannedCall{}, p.calls...)
+}
+
+type planContextKey struct{}
+
+// WithDryRun makes API calls that change Spotify state record themselves
+// on the returned plan instead of being sent. Reads still go to Spotify.
+// Values the API would have returned, such as snapshot IDs, are empty.
+func WithDryRun(ctx context.Context) (context.Context, *Plan) {
+	plan := &Plan{}
+	return context.WithValue(ctx, planContextKey{}, plan), plan
+}
+
+// PlanCall records a call that changes state when ctx is a dry run, and
+// reports whether it did, in which case the call must not be made. Other
+// API implementations use it to take part in dry runs.
+func PlanCall(ctx context.Context, method, url string, body interface{}) (bool, error) {
+	plan, ok := ctx.Value(planContextKey{}).(*Plan)
+	if !ok {
+		return false, nil
+	}
+
+	call := PlannedCall{Method: method, URL: url}
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return true, err
+		}
+		call.Body = data
+	}
+
+	plan.mu.Lock()
+	plan.calls = append(plan.calls, call)
+	plan.mu.Unlock()
+	return true, nil
+}
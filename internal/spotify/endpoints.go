@@ -0,0 +1,100 @@
+package spotify
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+)
+
+const (
+	defaultAPIBaseURL      = "https://api.spotify.com/v1/"
+	defaultAccountsBaseURL = "https://accounts.spotify.com/"
+)
+
+// endpoints are the Web API and Accounts service roots, each ending in a
+// slash.
+type endpoints struct {
+	api      string
+	accounts string
+}
+
+func newEndpoints(cfg config.SpotifyConfig) (endpoints, error) {
+	api, err := baseURL(cfg.APIBaseURL, defaultAPIBaseURL)
+	if err != nil {
+		return endpoints{}, fmt.Errorf("invalid api_base_url: %w", err)
+	}
+	accounts, err := baseURL(cfg.AccountsBaseURL, defaultAccountsBaseURL)
+	if err != nil {
+		return endpoints{}, fmt.Errorf("invalid accounts_base_url: %w", err)
+	}
+	return endpoints{api: api, accounts: accounts}, nil
+}
+
+func (e endpoints) tokenURL() string {
+	return e.accounts + "api/token"
+}
+
+func (e endpoints) authURL() string {
+	return e.accounts + "authorize"
+}
+
+func baseURL(raw, fallback string) (string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return fallback, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("%q is not an absolute http(s) URL", raw)
+	}
+	return strings.TrimSuffix(u.String(), "/") + "/", nil
+}
+
+// newBaseTransport returns the transport used for both token and API
+// requests, applying the configured proxy and CA bundle. Without a proxy
+// setting the standard HTTPS_PROXY/NO_PROXY environment is honoured.
+func newBaseTransport(cfg config.SpotifyConfig) (http.RoundTripper, error) {
+	if cfg.ProxyURL == "" && cfg.CAFile == "" {
+		return http.DefaultTransport, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.ProxyURL != "" {
+		proxy, err := url.Parse(cfg.ProxyURL)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy_url %q", cfg.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_file: %w", err)
+		}
+		// Trust the custom CA in addition to the system roots, so a TLS
+		// intercepting proxy and Spotify itself both verify
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in ca_file %s", cfg.CAFile)
+		}
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	return transport, nil
+}
@@ -0,0 +1,39 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetDevices lists the authorized user's available Spotify Connect devices.
+func (c *Client) GetDevices(ctx context.Context) ([]Device, error) {
+	if !c.userAuthorized {
+		return nil, ErrUserAuthRequired
+	}
+
+	devices, err := coalesce(ctx, &c.flight, flightKey("GetDevices"), c.getDevices)
+	if err != nil {
+		return nil, err
+	}
+	return *devices, nil
+}
+
+func (c *Client) getDevices(ctx context.Context) (*[]Device, error) {
+	devices, err := c.client.PlayerDevices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get devices: %w", err)
+	}
+
+	result := make([]Device, len(devices))
+	for i, device := range devices {
+		result[i] = Device{
+			ID:         string(device.ID),
+			Name:       device.Name,
+			Type:       device.Type,
+			IsActive:   device.Active,
+			Restricted: device.Restricted,
+			Volume:     int(device.Volume),
+		}
+	}
+	return &result, nil
+}
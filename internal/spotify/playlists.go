@@ -0,0 +1,262 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// maxPlaylistEditItems is Spotify's limit on the items added, removed or
+// set in one request. Larger edits are split into several requests.
+const maxPlaylistEditItems = 100
+
+// playlistItemPage is a page of playlist items as the Web API returns it.
+type playlistItemPage struct {
+	Items []struct {
+		Track *trackObject `json:"track"`
+	} `json:"items"`
+	Total spotify.Numeric `json:"total"`
+}
+
+// GetPlaylistItems returns every item in a playlist along with its current
+// snapshot ID. Cached pages are revalidated, since the result is the basis
+// for edits.
+func (c *Client) GetPlaylistItems(ctx context.Context, playlistID string) (*PlaylistItems, error) {
+	return coalesce(ctx, &c.flight, flightKey("GetPlaylistItems", playlistID), func(ctx context.Context) (*PlaylistItems, error) {
+		return c.getPlaylistItems(withRevalidation(ctx), playlistID)
+	})
+}
+
+func (c *Client) getPlaylistItems(ctx context.Context, playlistID string) (*PlaylistItems, error) {
+	path := "playlists/" + url.PathEscape(playlistID)
+
+	// The playlist itself carries the snapshot ID and the first page
+	var playlist struct {
+		spotify.SimplePlaylist
+		Tracks playlistItemPage `json:"tracks"`
+	}
+	if err := c.getJSON(ctx, path, nil, &playlist); err != nil {
+		return nil, fmt.Errorf("failed to get playlist items: %w", err)
+	}
+
+	result := &PlaylistItems{
+		PlaylistID: string(playlist.ID),
+		Name:       playlist.Name,
+		SnapshotID: playlist.SnapshotID,
+		Items:      []Track{},
+	}
+	page := playlist.Tracks
+	for offset := 0; ; {
+		for _, item := range page.Items {
+			// Removed tracks come back as null. They keep their place, so
+			// that positions match the ones edits are made at.
+			var track Track
+			if item.Track != nil {
+				track = newTrack(*item.Track)
+			}
+			result.Items = append(result.Items, track)
+		}
+
+		offset += len(page.Items)
+		if len(page.Items) == 0 || offset >= int(page.Total) {
+			break
+		}
+		params := url.Values{
+			"offset": {strconv.Itoa(offset)},
+			"limit":  {strconv.Itoa(maxPlaylistEditItems)},
+		}
+		page = playlistItemPage{}
+		if err := c.getJSON(ctx, path+"/tracks", params, &page); err != nil {
+			return nil, fmt.Errorf("failed to get playlist items: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// AddPlaylistItems inserts items, given as Spotify URIs, at position, or
+// appends them when position is nil. It returns the new snapshot ID.
+func (c *Client) AddPlaylistItems(ctx context.Context, playlistID string, uris []string, position *int) (string, error) {
+	if !c.userAuthorized {
+		return "", ErrUserAuthRequired
+	}
+
+	var snapshotID string
+	err := editInBatches(uris, func(start int, batch []string) error {
+		body := map[string]interface{}{"uris": batch}
+		if position != nil {
+			body["position"] = *position + start
+		}
+		var err error
+		snapshotID, err = c.editPlaylist(ctx, http.MethodPost, playlistID, body)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to add playlist items: %w", err)
+	}
+	return snapshotID, nil
+}
+
+// RemovePlaylistItems removes every occurrence of the items from a
+// playlist. A snapshot ID, if given, is the version the removal was
+// planned against. It returns the new snapshot ID.
+func (c *Client) RemovePlaylistItems(ctx context.Context, playlistID string, uris []string, snapshotID string) (string, error) {
+	if !c.userAuthorized {
+		return "", ErrUserAuthRequired
+	}
+
+	// Each request removes every occurrence, so duplicates are redundant
+	uris = slices.Compact(slices.Sorted(slices.Values(uris)))
+	err := editInBatches(uris, func(_ int, batch []string) error {
+		items := make([]map[string]string, len(batch))
+		for i, uri := range batch {
+			items[i] = map[string]string{"uri": uri}
+		}
+		body := map[string]interface{}{"tracks": items}
+		if snapshotID != "" {
+			body["snapshot_id"] = snapshotID
+		}
+		var err error
+		snapshotID, err = c.editPlaylist(ctx, http.MethodDelete, playlistID, body)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to remove playlist items: %w", err)
+	}
+	return snapshotID, nil
+}
+
+// RemovePlaylistItemsAt removes the items at the given positions, each of
+// which must hold the item's URI. Positions are counted in the version a
+// snapshot ID, if given, identifies. It returns the new snapshot ID.
+func (c *Client) RemovePlaylistItemsAt(ctx context.Context, playlistID string, items []PlaylistItemPosition, snapshotID string) (string, error) {
+	if !c.userAuthorized {
+		return "", ErrUserAuthRequired
+	}
+
+	// Later positions go first, so each batch leaves the positions of the
+	// next one, in the snapshot it returns, where they were
+	items = slices.SortedFunc(slices.Values(items), func(a, b PlaylistItemPosition) int {
+		return b.Position - a.Position
+	})
+	for start := 0; start < len(items); start += maxPlaylistEditItems {
+		body := map[string]interface{}{
+			"tracks": groupPositions(items[start:min(start+maxPlaylistEditItems, len(items))]),
+		}
+		if snapshotID != "" {
+			body["snapshot_id"] = snapshotID
+		}
+		var err error
+		if snapshotID, err = c.editPlaylist(ctx, http.MethodDelete, playlistID, body); err != nil {
+			if start > 0 {
+				err = fmt.Errorf("applied %d of %d items: %w", start, len(items), err)
+			}
+			return "", fmt.Errorf("failed to remove playlist items: %w", err)
+		}
+	}
+	return snapshotID, nil
+}
+
+// itemPositions are the positions of one URI in a positional removal.
+type itemPositions struct {
+	URI       string `json:"uri"`
+	Positions []int  `json:"positions"`
+}
+
+// groupPositions groups positions by URI, as Spotify expects them.
+func groupPositions(items []PlaylistItemPosition) []*itemPositions {
+	var tracks []*itemPositions
+	byURI := make(map[string]*itemPositions)
+	for _, item := range items {
+		track, ok := byURI[item.URI]
+		if !ok {
+			track = &itemPositions{URI: item.URI}
+			byURI[item.URI] = track
+			tracks = append(tracks, track)
+		}
+		track.Positions = append(track.Positions, item.Position)
+	}
+	return tracks
+}
+
+// ReplacePlaylistItems sets a playlist's items, clearing it when uris is
+// empty. It returns the new snapshot ID.
+func (c *Client) ReplacePlaylistItems(ctx context.Context, playlistID string, uris []string) (string, error) {
+	if !c.userAuthorized {
+		return "", ErrUserAuthRequired
+	}
+
+	// Only the first batch replaces; the rest are appended
+	first := uris[:min(len(uris), maxPlaylistEditItems)]
+	snapshotID, err := c.editPlaylist(ctx, http.MethodPut, playlistID, map[string]interface{}{"uris": first})
+	if err != nil {
+		return "", fmt.Errorf("failed to replace playlist items: %w", err)
+	}
+	if len(uris) > len(first) {
+		err = editInBatches(uris[len(first):], func(_ int, batch []string) error {
+			snapshotID, err = c.editPlaylist(ctx, http.MethodPost, playlistID, map[string]interface{}{"uris": batch})
+			return err
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to replace playlist items: %w", err)
+		}
+	}
+	return snapshotID, nil
+}
+
+// ReorderPlaylistItems moves the rangeLength items starting at rangeStart
+// to before the item at insertBefore, both positions counted before the
+// move. A snapshot ID, if given, is the version the move was planned
+// against. It returns the new snapshot ID.
+func (c *Client) ReorderPlaylistItems(ctx context.Context, playlistID string, rangeStart, rangeLength, insertBefore int, snapshotID string) (string, error) {
+	if !c.userAuthorized {
+		return "", ErrUserAuthRequired
+	}
+
+	body := map[string]interface{}{
+		"range_start":   rangeStart,
+		"range_length":  rangeLength,
+		"insert_before": insertBefore,
+	}
+	if snapshotID != "" {
+		body["snapshot_id"] = snapshotID
+	}
+	snapshotID, err := c.editPlaylist(ctx, http.MethodPut, playlistID, body)
+	if err != nil {
+		return "", fmt.Errorf("failed to reorder playlist items: %w", err)
+	}
+	return snapshotID, nil
+}
+
+// editPlaylist sends one edit to a playlist's items and returns the
+// resulting snapshot ID.
+func (c *Client) editPlaylist(ctx context.Context, method, playlistID string, body interface{}) (string, error) {
+	var result struct {
+		SnapshotID string `json:"snapshot_id"`
+	}
+	if err := c.sendJSON(ctx, method, "playlists/"+url.PathEscape(playlistID)+"/tracks", body, &result); err != nil {
+		return "", err
+	}
+	return result.SnapshotID, nil
+}
+
+// editInBatches calls edit for each run of at most maxPlaylistEditItems
+// URIs, stopping at the first error. Earlier batches stay applied, so the
+// error says how far the edit got.
+func editInBatches(uris []string, edit func(start int, batch []string) error) error {
+	for start := 0; start < len(uris); start += maxPlaylistEditItems {
+		end := min(start+maxPlaylistEditItems, len(uris))
+		if err := edit(start, uris[start:end]); err != nil {
+			if start > 0 {
+				return fmt.Errorf("applied %d of %d items: %w", start, len(uris), err)
+			}
+			return err
+		}
+	}
+	return nil
+}
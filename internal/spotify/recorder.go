@@ -0,0 +1,316 @@
+package spotify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/config"
+)
+
+// Recording modes
+const (
+	RecordingOff    = "off"
+	RecordingRecord = "record"
+	RecordingReplay = "replay"
+)
+
+const redacted = "REDACTED"
+
+// Credentials stripped from cassettes, as form fields or JSON keys
+var sensitiveFields = map[string]bool{
+	"access_token":  true,
+	"refresh_token": true,
+	"client_secret": true,
+	"code":          true,
+}
+
+// Headers kept in cassettes; everything else, notably Authorization, is
+// dropped.
+var (
+	recordedRequestHeaders  = []string{"Accept-Language", "Content-Type", "If-None-Match"}
+	recordedResponseHeaders = []string{"Content-Type", "ETag", "Retry-After"}
+)
+
+// withRecording wraps base according to the recording mode: record passes
+// traffic through to base and saves it, replay never calls base.
+func withRecording(base http.RoundTripper, cfg config.RecordingConfig) (http.RoundTripper, error) {
+	switch cfg.Mode {
+	case "", RecordingOff:
+		return base, nil
+	case RecordingRecord:
+		return newRecordTransport(base, cfg.Cassette)
+	case RecordingReplay:
+		return newReplayTransport(cfg.Cassette)
+	default:
+		return nil, fmt.Errorf("unknown recording mode %q", cfg.Mode)
+	}
+}
+
+// cassette is the on-disk form of a recorded session.
+type cassette struct {
+	Interactions []interaction `json:"interactions"`
+}
+
+type interaction struct {
+	Request    recordedRequest  `json:"request"`
+	Response   recordedResponse `json:"response"`
+	RecordedAt time.Time        `json:"recorded_at"`
+}
+
+type recordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type recordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// recordTransport passes requests through and appends each sanitized
+// exchange to a cassette file.
+type recordTransport struct {
+	next http.RoundTripper
+	path string
+
+	mu       sync.Mutex
+	cassette cassette
+}
+
+func newRecordTransport(next http.RoundTripper, path string) (*recordTransport, error) {
+	if path == "" {
+		return nil, fmt.Errorf("cassette path is required for record mode")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create cassette dir: %w", err)
+	}
+	return &recordTransport{next: next, path: path}, nil
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.cassette.Interactions = append(t.cassette.Interactions, interaction{
+		Request: recordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: filterHeader(req.Header, recordedRequestHeaders),
+			Body:   sanitizeBody(reqBody),
+		},
+		Response: recordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     filterHeader(resp.Header, recordedResponseHeaders),
+			Body:       sanitizeBody(respBody),
+		},
+		RecordedAt: time.Now().UTC(),
+	})
+
+	// Rewrite the whole cassette so it is complete even if the process
+	// is killed
+	if err := t.save(); err != nil {
+		return nil, fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return resp, nil
+}
+
+func (t *recordTransport) save() error {
+	data, err := json.MarshalIndent(t.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.path)
+}
+
+// replayTransport serves responses from a cassette without touching the
+// network. Identical requests are answered in recorded order; once they
+// run out the last response repeats.
+type replayTransport struct {
+	mu      sync.Mutex
+	queues  map[string][]recordedResponse
+	current map[string]int
+}
+
+func newReplayTransport(path string) (*replayTransport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var c cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+
+	t := &replayTransport{
+		queues:  make(map[string][]recordedResponse),
+		current: make(map[string]int),
+	}
+	for _, i := range c.Interactions {
+		u, err := url.Parse(i.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("cassette %s: %w", path, err)
+		}
+		key := interactionKey(i.Request.Method, u, i.Request.Body)
+		t.queues[key] = append(t.queues[key], i.Response)
+	}
+	return t, nil
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	key := interactionKey(req.Method, req.URL, sanitizeBody(body))
+
+	t.mu.Lock()
+	queue := t.queues[key]
+	if len(queue) == 0 {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("replay: no recorded response for %s %s", req.Method, req.URL)
+	}
+	n := t.current[key]
+	if n < len(queue)-1 {
+		t.current[key] = n + 1
+	}
+	recorded := queue[n]
+	t.mu.Unlock()
+
+	header := recorded.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// interactionKey identifies a request independently of query parameter
+// order and of the host it was sent to.
+func interactionKey(method string, u *url.URL, body string) string {
+	return method + " " + u.Path + "?" + u.Query().Encode() + " " + body
+}
+
+// readBody returns the request body and restores it for the next reader.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func filterHeader(header http.Header, keep []string) http.Header {
+	filtered := http.Header{}
+	for _, name := range keep {
+		if values := header.Values(name); len(values) > 0 {
+			filtered[name] = values
+		}
+	}
+	if len(filtered) == 0 {
+		return nil
+	}
+	return filtered
+}
+
+// sanitizeBody redacts credentials from JSON and form-encoded bodies.
+// Redaction is deterministic so replayed requests still match.
+func sanitizeBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err == nil {
+		if redactJSON(doc) {
+			if data, err := json.Marshal(doc); err == nil {
+				return string(data)
+			}
+		}
+		return string(body)
+	}
+
+	if form, err := url.ParseQuery(string(body)); err == nil {
+		changed := false
+		for field := range form {
+			if sensitiveFields[field] {
+				form.Set(field, redacted)
+				changed = true
+			}
+		}
+		if changed {
+			return form.Encode()
+		}
+	}
+	return string(body)
+}
+
+func redactJSON(v interface{}) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if sensitiveFields[key] {
+				v[key] = redacted
+				changed = true
+				continue
+			}
+			if redactJSON(value) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			if redactJSON(value) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,573 @@
+package spotifytest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/anuragkothare/spotify_mcp_server/internal/spotify"
+)
+
+// apiBaseURL is the Web API base the fake's dry runs report calls against.
+const apiBaseURL = "https://api.spotify.com/v1/"
+
+// Fake is an in-memory spotify.API backed by the fixture catalog.
+type Fake struct {
+	// Authorized makes the fake behave as if it held a user token.
+	Authorized bool
+	// Err, when set, is returned by every Spotify operation.
+	Err error
+	// Breaker is reported by BreakerStatus; the zero value is closed.
+	Breaker spotify.BreakerStatus
+	// Product, when set, replaces the fixture user's subscription level,
+	// e.g. "free".
+	Product string
+
+	mu      sync.Mutex
+	calls   map[string]int
+	catalog *catalog
+}
+
+var _ spotify.API = (*Fake)(nil)
+
+// NewFake returns a user-authorized fake serving the fixture catalog.
+func NewFake() *Fake {
+	return &Fake{
+		Authorized: true,
+		Breaker:    spotify.BreakerStatus{State: spotify.BreakerClosed},
+		calls:      make(map[string]int),
+		catalog:    mustLoadCatalog(),
+	}
+}
+
+// Calls returns how many times the named method was called.
+func (f *Fake) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+func (f *Fake) call(ctx context.Context, method string) error {
+	f.mu.Lock()
+	f.calls[method]++
+	f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.Err
+}
+
+func (f *Fake) SearchTracks(ctx context.Context, query string, limit int, market string) (*spotify.SearchResult, error) {
+	if err := f.call(ctx, "SearchTracks"); err != nil {
+		return nil, err
+	}
+
+	matches := f.catalog.searchTracks(query)
+	start, end := page(len(matches), 0, limit)
+
+	tracks := make([]spotify.Track, 0, end-start)
+	for _, track := range matches[start:end] {
+		tracks = append(tracks, newTrack(track, market))
+	}
+	return &spotify.SearchResult{Tracks: tracks, Total: len(matches)}, nil
+}
+
+func (f *Fake) SearchArtists(ctx context.Context, query string, limit int, market string) (*spotify.ArtistSearchResult, error) {
+	if err := f.call(ctx, "SearchArtists"); err != nil {
+		return nil, err
+	}
+
+	matches := f.catalog.searchArtists(query)
+	start, end := page(len(matches), 0, limit)
+
+	artists := make([]spotify.Artist, 0, end-start)
+	for _, artist := range matches[start:end] {
+		artists = append(artists, spotify.Artist{
+			ID:         artist.ID,
+			Name:       artist.Name,
+			Popularity: artist.Popularity,
+			URI:        artist.URI,
+		})
+	}
+	return &spotify.ArtistSearchResult{Artists: artists, Total: len(matches)}, nil
+}
+
+func (f *Fake) GetTrack(ctx context.Context, trackID string, market string) (*spotify.Track, error) {
+	if err := f.call(ctx, "GetTrack"); err != nil {
+		return nil, err
+	}
+
+	fixture := f.catalog.track(trackID)
+	if fixture == nil {
+		return nil, fmt.Errorf("failed to get track: %w", notFound())
+	}
+	track := newTrack(fixture, market)
+	return &track, nil
+}
+
+func (f *Fake) GetArtist(ctx context.Context, artistID string) (*spotify.Artist, error) {
+	if err := f.call(ctx, "GetArtist"); err != nil {
+		return nil, err
+	}
+
+	artist := f.catalog.artist(artistID)
+	if artist == nil {
+		return nil, fmt.Errorf("failed to get artist: %w", notFound())
+	}
+	return &spotify.Artist{
+		ID:         artist.ID,
+		Name:       artist.Name,
+		Popularity: artist.Popularity,
+		URI:        artist.URI,
+		Genres:     artist.Genres,
+		Followers:  artist.Followers.Total,
+	}, nil
+}
+
+func (f *Fake) GetAlbum(ctx context.Context, albumID string, market string) (*spotify.Album, error) {
+	if err := f.call(ctx, "GetAlbum"); err != nil {
+		return nil, err
+	}
+
+	album := f.catalog.album(albumID)
+	if album == nil {
+		return nil, fmt.Errorf("failed to get album: %w", notFound())
+	}
+
+	artistName := "Unknown Artist"
+	if len(album.Artists) > 0 {
+		artistName = album.Artists[0].Name
+	}
+
+	tracks := make([]spotify.Track, 0, len(album.Tracks.Items))
+	for _, item := range album.Tracks.Items {
+		if track := f.catalog.track(item.ID); track != nil {
+			tracks = append(tracks, newTrack(track, market))
+		}
+	}
+	return &spotify.Album{
+		ID:          album.ID,
+		Name:        album.Name,
+		Artist:      artistName,
+		ReleaseDate: album.ReleaseDate,
+		TotalTracks: album.TotalTracks,
+		Tracks:      tracks,
+		URI:         album.URI,
+	}, nil
+}
+
+func (f *Fake) GetPlaylist(ctx context.Context, playlistID string, market string) (*spotify.Playlist, error) {
+	if err := f.call(ctx, "GetPlaylist"); err != nil {
+		return nil, err
+	}
+
+	fixture := f.catalog.playlist(playlistID)
+	if fixture == nil {
+		return nil, fmt.Errorf("failed to get playlist: %w", notFound())
+	}
+
+	playlist := newPlaylist(fixture)
+	playlist.TrackCount = f.catalog.playlistTotal(fixture)
+	playlist.SnapshotID = f.catalog.snapshotID(playlistID)
+	playlist.Tracks = []spotify.Track{}
+	for _, track := range f.catalog.playlistTracks(playlistID) {
+		if track != nil {
+			playlist.Tracks = append(playlist.Tracks, newTrack(track, market))
+		}
+	}
+	return &playlist, nil
+}
+
+func (f *Fake) GetCategories(ctx context.Context, opts spotify.BrowseOptions) (*spotify.CategoryPage, error) {
+	if err := f.call(ctx, "GetCategories"); err != nil {
+		return nil, err
+	}
+
+	all := f.catalog.categories
+	start, end := page(len(all), opts.Offset, opts.Limit)
+
+	categories := make([]spotify.Category, 0, end-start)
+	for _, category := range all[start:end] {
+		categories = append(categories, spotify.Category{ID: category.ID, Name: category.Name})
+	}
+	return &spotify.CategoryPage{
+		Categories: categories,
+		Total:      len(all),
+		Limit:      opts.Limit,
+		Offset:     start,
+	}, nil
+}
+
+func (f *Fake) GetCategoryPlaylists(ctx context.Context, categoryID string, opts spotify.BrowseOptions) (*spotify.PlaylistPage, error) {
+	if err := f.call(ctx, "GetCategoryPlaylists"); err != nil {
+		return nil, err
+	}
+
+	all, ok := f.catalog.playlists[categoryID]
+	if !ok {
+		return nil, fmt.Errorf("failed to get category playlists: %w", notFound())
+	}
+	start, end := page(len(all), opts.Offset, opts.Limit)
+
+	playlists := make([]spotify.Playlist, 0, end-start)
+	for _, playlist := range all[start:end] {
+		if playlist == nil {
+			continue
+		}
+		playlists = append(playlists, newPlaylist(playlist))
+	}
+	return &spotify.PlaylistPage{
+		Playlists: playlists,
+		Total:     len(all),
+		Limit:     opts.Limit,
+		Offset:    start,
+	}, nil
+}
+
+func (f *Fake) GetCurrentUser(ctx context.Context) (*spotify.CurrentUser, error) {
+	if err := f.call(ctx, "GetCurrentUser"); err != nil {
+		return nil, err
+	}
+	if !f.Authorized {
+		return nil, spotify.ErrUserAuthRequired
+	}
+
+	me := f.catalog.me
+	product := me.Product
+	if f.Product != "" {
+		product = f.Product
+	}
+	return &spotify.CurrentUser{
+		User:    newUser(me),
+		Email:   me.Email,
+		Country: me.Country,
+		Product: product,
+		ExplicitContent: spotify.ExplicitContent{
+			FilterEnabled: me.ExplicitContent.FilterEnabled,
+			FilterLocked:  me.ExplicitContent.FilterLocked,
+		},
+	}, nil
+}
+
+func (f *Fake) GetUserProfile(ctx context.Context, userID string) (*spotify.User, error) {
+	if err := f.call(ctx, "GetUserProfile"); err != nil {
+		return nil, err
+	}
+
+	fixture := f.catalog.user(userID)
+	if fixture == nil {
+		return nil, fmt.Errorf("failed to get user profile: %w", notFound())
+	}
+	user := newUser(fixture)
+	return &user, nil
+}
+
+func (f *Fake) GetMyPlaylists(ctx context.Context, limit, offset int) (*spotify.PlaylistPage, error) {
+	if err := f.call(ctx, "GetMyPlaylists"); err != nil {
+		return nil, err
+	}
+	if !f.Authorized {
+		return nil, spotify.ErrUserAuthRequired
+	}
+
+	all := f.catalog.myPlaylists
+	start, end := page(len(all), offset, limit)
+
+	playlists := make([]spotify.Playlist, 0, end-start)
+	for _, playlist := range all[start:end] {
+		playlists = append(playlists, newPlaylist(playlist))
+	}
+	return &spotify.PlaylistPage{
+		Playlists: playlists,
+		Total:     len(all),
+		Limit:     limit,
+		Offset:    start,
+	}, nil
+}
+
+// GetTopItems returns the catalog's artists and tracks in fixture order,
+// whatever the time range.
+func (f *Fake) GetTopItems(ctx context.Context, timeRange string, limit int) (*spotify.TopItems, error) {
+	if err := f.call(ctx, "GetTopItems"); err != nil {
+		return nil, err
+	}
+	if !f.Authorized {
+		return nil, spotify.ErrUserAuthRequired
+	}
+	if !slices.Contains(spotify.TimeRanges, timeRange) {
+		return nil, fmt.Errorf("unknown time range %q", timeRange)
+	}
+
+	items := &spotify.TopItems{TimeRange: timeRange}
+	for _, artist := range f.catalog.artists[:min(limit, len(f.catalog.artists))] {
+		items.Artists = append(items.Artists, spotify.Artist{
+			ID:         artist.ID,
+			Name:       artist.Name,
+			Popularity: artist.Popularity,
+			URI:        artist.URI,
+			Genres:     artist.Genres,
+			Followers:  artist.Followers.Total,
+		})
+	}
+	for _, track := range f.catalog.tracks[:min(limit, len(f.catalog.tracks))] {
+		items.Tracks = append(items.Tracks, newTrack(track, ""))
+	}
+	return items, nil
+}
+
+func (f *Fake) GetDevices(ctx context.Context) ([]spotify.Device, error) {
+	if err := f.call(ctx, "GetDevices"); err != nil {
+		return nil, err
+	}
+	if !f.Authorized {
+		return nil, spotify.ErrUserAuthRequired
+	}
+
+	devices := make([]spotify.Device, len(f.catalog.devices))
+	for i, device := range f.catalog.devices {
+		devices[i] = spotify.Device{
+			ID:         device.ID,
+			Name:       device.Name,
+			Type:       device.Type,
+			IsActive:   device.IsActive,
+			Restricted: device.Restricted,
+			Volume:     device.Volume,
+		}
+	}
+	return devices, nil
+}
+
+func (f *Fake) GetPlaylistItems(ctx context.Context, playlistID string) (*spotify.PlaylistItems, error) {
+	if err := f.call(ctx, "GetPlaylistItems"); err != nil {
+		return nil, err
+	}
+
+	fixture := f.catalog.playlist(playlistID)
+	if fixture == nil {
+		return nil, fmt.Errorf("failed to get playlist items: %w", notFound())
+	}
+	items := &spotify.PlaylistItems{
+		PlaylistID: fixture.ID,
+		Name:       fixture.Name,
+		SnapshotID: f.catalog.snapshotID(playlistID),
+		Items:      []spotify.Track{},
+	}
+	for _, track := range f.catalog.playlistTracks(playlistID) {
+		item := spotify.Track{}
+		if track != nil {
+			item = newTrack(track, "")
+		}
+		items.Items = append(items.Items, item)
+	}
+	return items, nil
+}
+
+// AddPlaylistItems, like the other edits, changes the fake's catalog, so
+// later reads see the edit. Under a dry run, edits are only recorded.
+// Unlike the client it doesn't split large edits, and rejects them as
+// Spotify would a single oversized request.
+func (f *Fake) AddPlaylistItems(ctx context.Context, playlistID string, uris []string, position *int) (string, error) {
+	if err := f.edit(ctx, "AddPlaylistItems"); err != nil {
+		return "", err
+	}
+	body := map[string]interface{}{"uris": uris}
+	if position != nil {
+		body["position"] = *position
+	}
+	if planned, err := planEdit(ctx, http.MethodPost, playlistID, body); planned {
+		return "", err
+	}
+	snapshotID, err := f.catalog.addItems(playlistID, uris, position)
+	if err != nil {
+		return "", fmt.Errorf("failed to add playlist items: %w", err)
+	}
+	return snapshotID, nil
+}
+
+func (f *Fake) RemovePlaylistItems(ctx context.Context, playlistID string, uris []string, snapshotID string) (string, error) {
+	if err := f.edit(ctx, "RemovePlaylistItems"); err != nil {
+		return "", err
+	}
+	items := make([]map[string]string, len(uris))
+	for i, uri := range uris {
+		items[i] = map[string]string{"uri": uri}
+	}
+	body := map[string]interface{}{"tracks": items}
+	if snapshotID != "" {
+		body["snapshot_id"] = snapshotID
+	}
+	if planned, err := planEdit(ctx, http.MethodDelete, playlistID, body); planned {
+		return "", err
+	}
+	snapshotID, err := f.catalog.removeItems(playlistID, uris)
+	if err != nil {
+		return "", fmt.Errorf("failed to remove playlist items: %w", err)
+	}
+	return snapshotID, nil
+}
+
+func (f *Fake) RemovePlaylistItemsAt(ctx context.Context, playlistID string, items []spotify.PlaylistItemPosition, snapshotID string) (string, error) {
+	if err := f.edit(ctx, "RemovePlaylistItemsAt"); err != nil {
+		return "", err
+	}
+	// Grouped by URI, later positions first, as the client sends them
+	items = slices.SortedFunc(slices.Values(items), func(a, b spotify.PlaylistItemPosition) int {
+		return b.Position - a.Position
+	})
+	type itemPositions struct {
+		URI       string `json:"uri"`
+		Positions []int  `json:"positions"`
+	}
+	var tracks []*itemPositions
+	byURI := make(map[string]*itemPositions)
+	for _, item := range items {
+		track, ok := byURI[item.URI]
+		if !ok {
+			track = &itemPositions{URI: item.URI}
+			byURI[item.URI] = track
+			tracks = append(tracks, track)
+		}
+		track.Positions = append(track.Positions, item.Position)
+	}
+	body := map[string]interface{}{"tracks": tracks}
+	if snapshotID != "" {
+		body["snapshot_id"] = snapshotID
+	}
+	if planned, err := planEdit(ctx, http.MethodDelete, playlistID, body); planned {
+		return "", err
+	}
+
+	uris := make([]string, len(items))
+	positions := make([]int, len(items))
+	for i, item := range items {
+		uris[i], positions[i] = item.URI, item.Position
+	}
+	snapshotID, err := f.catalog.removeItemsAt(playlistID, uris, positions)
+	if err != nil {
+		return "", fmt.Errorf("failed to remove playlist items: %w", err)
+	}
+	return snapshotID, nil
+}
+
+func (f *Fake) ReplacePlaylistItems(ctx context.Context, playlistID string, uris []string) (string, error) {
+	if err := f.edit(ctx, "ReplacePlaylistItems"); err != nil {
+		return "", err
+	}
+	if planned, err := planEdit(ctx, http.MethodPut, playlistID, map[string]interface{}{"uris": uris}); planned {
+		return "", err
+	}
+	snapshotID, err := f.catalog.replaceItems(playlistID, uris)
+	if err != nil {
+		return "", fmt.Errorf("failed to replace playlist items: %w", err)
+	}
+	return snapshotID, nil
+}
+
+func (f *Fake) ReorderPlaylistItems(ctx context.Context, playlistID string, rangeStart, rangeLength, insertBefore int, snapshotID string) (string, error) {
+	if err := f.edit(ctx, "ReorderPlaylistItems"); err != nil {
+		return "", err
+	}
+	body := map[string]interface{}{
+		"range_start":   rangeStart,
+		"range_length":  rangeLength,
+		"insert_before": insertBefore,
+	}
+	if snapshotID != "" {
+		body["snapshot_id"] = snapshotID
+	}
+	if planned, err := planEdit(ctx, http.MethodPut, playlistID, body); planned {
+		return "", err
+	}
+	snapshotID, err := f.catalog.reorderItems(playlistID, rangeStart, rangeLength, insertBefore)
+	if err != nil {
+		return "", fmt.Errorf("failed to reorder playlist items: %w", err)
+	}
+	return snapshotID, nil
+}
+
+// planEdit records an edit under a dry run as the request the client
+// would send for it.
+func planEdit(ctx context.Context, method, playlistID string, body interface{}) (bool, error) {
+	return spotify.PlanCall(ctx, method, apiBaseURL+"playlists/"+url.PathEscape(playlistID)+"/tracks", body)
+}
+
+// edit counts a call that needs a user token.
+func (f *Fake) edit(ctx context.Context, method string) error {
+	if err := f.call(ctx, method); err != nil {
+		return err
+	}
+	if !f.Authorized {
+		return spotify.ErrUserAuthRequired
+	}
+	return nil
+}
+
+func (f *Fake) UserAuthorized() bool {
+	return f.Authorized
+}
+
+// CacheStats returns nil: the fake has no cache.
+func (f *Fake) CacheStats() *spotify.CacheStats {
+	return nil
+}
+
+func (f *Fake) BreakerStatus() spotify.BreakerStatus {
+	return f.Breaker
+}
+
+// newTrack mirrors the client's conversion: playability is only reported
+// when a market is given.
+func newTrack(track *fixtureTrack, market string) spotify.Track {
+	artistName := "Unknown Artist"
+	if len(track.Artists) > 0 {
+		artistName = track.Artists[0].Name
+	}
+
+	result := spotify.Track{
+		ID:     track.ID,
+		Name:   track.Name,
+		Artist: artistName,
+		Album:  track.Album.Name,
+		URI:    track.URI,
+	}
+	if strings.TrimSpace(market) != "" {
+		result.IsPlayable = track.IsPlayable
+		if track.Restrictions != nil {
+			result.Restriction = track.Restrictions.Reason
+		}
+	}
+	return result
+}
+
+func newPlaylist(playlist *fixturePlaylist) spotify.Playlist {
+	owner := playlist.Owner.DisplayName
+	if owner == "" {
+		owner = playlist.Owner.ID
+	}
+	return spotify.Playlist{
+		ID:          playlist.ID,
+		Name:        playlist.Name,
+		Description: playlist.Description,
+		Owner:       owner,
+		TrackCount:  playlist.Tracks.Total,
+		URI:         playlist.URI,
+	}
+}
+
+func newUser(user *fixtureUser) spotify.User {
+	return spotify.User{
+		ID:          user.ID,
+		DisplayName: user.DisplayName,
+		Followers:   user.Followers.Total,
+		URI:         user.URI,
+	}
+}
+
+func notFound() error {
+	return spotifyError(404, "Non existing id")
+}
@@ -0,0 +1,504 @@
+// Package spotifytest provides offline stand-ins for the Spotify Web API:
+// Fake, an in-memory implementation of spotify.API, and Server, an httptest
+// server speaking the Web API and token endpoint. Both serve the catalog in
+// fixtures/, which is stored in Web API response format.
+package spotifytest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+//go:embed fixtures/*.json
+var fixtureFS embed.FS
+
+type fixtureTrack struct {
+	raw json.RawMessage
+
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	URI     string `json:"uri"`
+	Artists []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+	Album struct {
+		Name string `json:"name"`
+	} `json:"album"`
+	IsPlayable   *bool `json:"is_playable"`
+	Restrictions *struct {
+		Reason string `json:"reason"`
+	} `json:"restrictions"`
+}
+
+type fixtureArtist struct {
+	raw json.RawMessage
+
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Popularity int      `json:"popularity"`
+	URI        string   `json:"uri"`
+	Genres     []string `json:"genres"`
+	Followers  struct {
+		Total int `json:"total"`
+	} `json:"followers"`
+}
+
+type fixtureAlbum struct {
+	raw json.RawMessage
+
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	URI         string `json:"uri"`
+	ReleaseDate string `json:"release_date"`
+	TotalTracks int    `json:"total_tracks"`
+	Artists     []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+	Tracks struct {
+		Items []struct {
+			ID string `json:"id"`
+		} `json:"items"`
+	} `json:"tracks"`
+}
+
+type fixtureCategory struct {
+	raw json.RawMessage
+
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type fixturePlaylist struct {
+	raw json.RawMessage
+
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Owner       struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"display_name"`
+	} `json:"owner"`
+	Tracks struct {
+		Total int `json:"total"`
+	} `json:"tracks"`
+	URI string `json:"uri"`
+}
+
+type fixtureDevice struct {
+	raw json.RawMessage
+
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	IsActive   bool   `json:"is_active"`
+	Restricted bool   `json:"is_restricted"`
+	Volume     int    `json:"volume_percent"`
+}
+
+type fixtureUser struct {
+	raw json.RawMessage
+
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+	Followers   struct {
+		Total int `json:"total"`
+	} `json:"followers"`
+	URI string `json:"uri"`
+
+	// Private fields, only present in me.json
+	Email           string `json:"email"`
+	Country         string `json:"country"`
+	Product         string `json:"product"`
+	ExplicitContent struct {
+		FilterEnabled bool `json:"filter_enabled"`
+		FilterLocked  bool `json:"filter_locked"`
+	} `json:"explicit_content"`
+}
+
+// catalog is the decoded fixture set. Every entry keeps its raw JSON so
+// Server can return it verbatim.
+type catalog struct {
+	tracks     []*fixtureTrack
+	artists    []*fixtureArtist
+	albums     []*fixtureAlbum
+	categories []*fixtureCategory
+	// playlists by category; nil entries mirror the nulls Spotify returns
+	// for unavailable playlists
+	playlists map[string][]*fixturePlaylist
+	// myPlaylists are the playlists the fixture user owns or follows
+	myPlaylists []*fixturePlaylist
+	// mu guards playlistItems and versions, which playlist edits change
+	mu sync.Mutex
+	// playlistItems lists the track IDs in each playlist
+	playlistItems map[string][]string
+	// versions counts the edits made to each playlist; see snapshotID
+	versions map[string]int
+	devices  []*fixtureDevice
+	users    []*fixtureUser
+	me       *fixtureUser
+}
+
+func loadCatalog() (*catalog, error) {
+	c := &catalog{versions: make(map[string]int)}
+	if err := loadList("tracks.json", &c.tracks); err != nil {
+		return nil, err
+	}
+	if err := loadList("artists.json", &c.artists); err != nil {
+		return nil, err
+	}
+	if err := loadList("albums.json", &c.albums); err != nil {
+		return nil, err
+	}
+	if err := loadList("categories.json", &c.categories); err != nil {
+		return nil, err
+	}
+	if err := loadList("users.json", &c.users); err != nil {
+		return nil, err
+	}
+
+	var playlists map[string][]json.RawMessage
+	if err := readFixture("playlists.json", &playlists); err != nil {
+		return nil, err
+	}
+	c.playlists = make(map[string][]*fixturePlaylist, len(playlists))
+	for category, raws := range playlists {
+		for _, raw := range raws {
+			var playlist *fixturePlaylist
+			if string(raw) != "null" {
+				playlist = &fixturePlaylist{raw: raw}
+				if err := json.Unmarshal(raw, playlist); err != nil {
+					return nil, fmt.Errorf("playlists.json: %w", err)
+				}
+			}
+			c.playlists[category] = append(c.playlists[category], playlist)
+		}
+	}
+
+	if err := loadList("my_playlists.json", &c.myPlaylists); err != nil {
+		return nil, err
+	}
+	if err := loadList("devices.json", &c.devices); err != nil {
+		return nil, err
+	}
+	if err := readFixture("playlist_items.json", &c.playlistItems); err != nil {
+		return nil, err
+	}
+
+	var me json.RawMessage
+	if err := readFixture("me.json", &me); err != nil {
+		return nil, err
+	}
+	c.me = &fixtureUser{raw: me}
+	if err := json.Unmarshal(me, c.me); err != nil {
+		return nil, fmt.Errorf("me.json: %w", err)
+	}
+
+	return c, nil
+}
+
+// mustLoadCatalog panics on malformed fixtures, which are embedded and so
+// can only be broken at development time.
+func mustLoadCatalog() *catalog {
+	c, err := loadCatalog()
+	if err != nil {
+		panic(fmt.Sprintf("spotifytest: %v", err))
+	}
+	return c
+}
+
+func readFixture(name string, v interface{}) error {
+	data, err := fixtureFS.ReadFile("fixtures/" + name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// loadList decodes a JSON array fixture into items that keep their raw JSON.
+func loadList[T any, P interface {
+	*T
+	setRaw(json.RawMessage)
+}](name string, items *[]P) error {
+	var raws []json.RawMessage
+	if err := readFixture(name, &raws); err != nil {
+		return err
+	}
+	for _, raw := range raws {
+		item := P(new(T))
+		if err := json.Unmarshal(raw, item); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		item.setRaw(raw)
+		*items = append(*items, item)
+	}
+	return nil
+}
+
+func (t *fixtureTrack) setRaw(raw json.RawMessage)    { t.raw = raw }
+func (a *fixtureArtist) setRaw(raw json.RawMessage)   { a.raw = raw }
+func (a *fixtureAlbum) setRaw(raw json.RawMessage)    { a.raw = raw }
+func (c *fixtureCategory) setRaw(raw json.RawMessage) { c.raw = raw }
+func (p *fixturePlaylist) setRaw(raw json.RawMessage) { p.raw = raw }
+func (d *fixtureDevice) setRaw(raw json.RawMessage)   { d.raw = raw }
+func (u *fixtureUser) setRaw(raw json.RawMessage)     { u.raw = raw }
+
+func (c *catalog) track(id string) *fixtureTrack {
+	for _, track := range c.tracks {
+		if track.ID == id {
+			return track
+		}
+	}
+	return nil
+}
+
+func (c *catalog) artist(id string) *fixtureArtist {
+	for _, artist := range c.artists {
+		if artist.ID == id {
+			return artist
+		}
+	}
+	return nil
+}
+
+func (c *catalog) album(id string) *fixtureAlbum {
+	for _, album := range c.albums {
+		if album.ID == id {
+			return album
+		}
+	}
+	return nil
+}
+
+// playlist looks a playlist up in the user's playlists and across all
+// categories.
+func (c *catalog) playlist(id string) *fixturePlaylist {
+	for _, playlist := range c.myPlaylists {
+		if playlist.ID == id {
+			return playlist
+		}
+	}
+	for _, playlists := range c.playlists {
+		for _, playlist := range playlists {
+			if playlist != nil && playlist.ID == id {
+				return playlist
+			}
+		}
+	}
+	return nil
+}
+
+// playlistTracks returns the tracks of a playlist in order. IDs missing
+// from the catalog stand for tracks Spotify removed, and are nil.
+func (c *catalog) playlistTracks(id string) []*fixtureTrack {
+	c.mu.Lock()
+	ids := c.playlistItems[id]
+	c.mu.Unlock()
+
+	tracks := make([]*fixtureTrack, len(ids))
+	for i, trackID := range ids {
+		tracks[i] = c.track(trackID)
+	}
+	return tracks
+}
+
+// playlistTotal is the playlist's track count: the fixture's until the
+// playlist is edited, then the number of items it holds.
+func (c *catalog) playlistTotal(playlist *fixturePlaylist) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.versions[playlist.ID] == 0 {
+		return playlist.Tracks.Total
+	}
+	return len(c.playlistItems[playlist.ID])
+}
+
+// snapshotID identifies the current version of a playlist.
+func (c *catalog) snapshotID(id string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return snapshotID(id, c.versions[id])
+}
+
+func snapshotID(id string, version int) string {
+	return fmt.Sprintf("%s-v%d", id, version)
+}
+
+// maxEditItems is Spotify's limit on the items in one playlist edit.
+const maxEditItems = 100
+
+// addItems inserts tracks at position, or appends them when it is nil.
+func (c *catalog) addItems(id string, uris []string, position *int) (string, error) {
+	return c.editPlaylist(id, uris, func(items, ids []string) ([]string, error) {
+		at := len(items)
+		if position != nil {
+			if *position < 0 || *position > len(items) {
+				return nil, spotifyError(400, "Index out of bounds")
+			}
+			at = *position
+		}
+		return slices.Insert(items, at, ids...), nil
+	})
+}
+
+// removeItems removes every occurrence of the tracks.
+func (c *catalog) removeItems(id string, uris []string) (string, error) {
+	return c.editPlaylist(id, uris, func(items, ids []string) ([]string, error) {
+		return slices.DeleteFunc(items, func(item string) bool {
+			return slices.Contains(ids, item)
+		}), nil
+	})
+}
+
+// removeItemsAt removes the tracks at positions, each of which must hold
+// the track at the same index of uris.
+func (c *catalog) removeItemsAt(id string, uris []string, positions []int) (string, error) {
+	return c.editPlaylist(id, uris, func(items, ids []string) ([]string, error) {
+		remove := make([]bool, len(items))
+		for i, position := range positions {
+			if position < 0 || position >= len(items) || items[position] != ids[i] {
+				return nil, spotifyError(400, "Could not remove tracks, please check parameters.")
+			}
+			remove[position] = true
+		}
+		kept := items[:0]
+		for i, item := range items {
+			if !remove[i] {
+				kept = append(kept, item)
+			}
+		}
+		return kept, nil
+	})
+}
+
+// replaceItems sets the playlist's items to the tracks.
+func (c *catalog) replaceItems(id string, uris []string) (string, error) {
+	return c.editPlaylist(id, uris, func(_, ids []string) ([]string, error) {
+		return ids, nil
+	})
+}
+
+// reorderItems moves length items from start to before insertBefore.
+func (c *catalog) reorderItems(id string, start, length, insertBefore int) (string, error) {
+	return c.editPlaylist(id, nil, func(items, _ []string) ([]string, error) {
+		if start < 0 || length < 1 || start+length > len(items) || insertBefore < 0 || insertBefore > len(items) {
+			return nil, spotifyError(400, "Index out of bounds")
+		}
+		return moveItems(items, start, length, insertBefore), nil
+	})
+}
+
+// moveItems moves length items from start to before insertBefore, both
+// positions counted before the move.
+func moveItems(items []string, start, length, insertBefore int) []string {
+	if insertBefore >= start && insertBefore <= start+length {
+		return items
+	}
+	moved := slices.Clone(items[start : start+length])
+	rest := slices.Delete(slices.Clone(items), start, start+length)
+	if insertBefore > start {
+		insertBefore -= length
+	}
+	return slices.Insert(rest, insertBefore, moved...)
+}
+
+// editPlaylist applies an edit to a playlist the fixture user owns, as
+// Spotify only allows, and returns the new snapshot ID. Only fixture
+// tracks can be added or removed.
+func (c *catalog) editPlaylist(id string, uris []string, edit func(items, ids []string) ([]string, error)) (string, error) {
+	playlist := c.playlist(id)
+	if playlist == nil {
+		return "", spotifyError(404, "Not found.")
+	}
+	if playlist.Owner.ID != c.me.ID {
+		return "", spotifyError(403, "You cannot edit a playlist you don't own.")
+	}
+	if len(uris) > maxEditItems {
+		return "", spotifyError(400, "Too many items in request")
+	}
+
+	ids := make([]string, len(uris))
+	for i, uri := range uris {
+		track := c.track(strings.TrimPrefix(uri, "spotify:track:"))
+		if track == nil || track.URI != uri {
+			return "", spotifyError(400, "Invalid track uri: "+uri)
+		}
+		ids[i] = track.ID
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	items, err := edit(slices.Clone(c.playlistItems[id]), ids)
+	if err != nil {
+		return "", err
+	}
+	c.playlistItems[id] = items
+	c.versions[id]++
+	return snapshotID(id, c.versions[id]), nil
+}
+
+func (c *catalog) user(id string) *fixtureUser {
+	for _, user := range c.users {
+		if user.ID == id {
+			return user
+		}
+	}
+	return nil
+}
+
+// searchTracks matches the query against track and artist names.
+func (c *catalog) searchTracks(query string) []*fixtureTrack {
+	var matches []*fixtureTrack
+	for _, track := range c.tracks {
+		names := []string{track.Name}
+		for _, artist := range track.Artists {
+			names = append(names, artist.Name)
+		}
+		if matchesQuery(query, names...) {
+			matches = append(matches, track)
+		}
+	}
+	return matches
+}
+
+func (c *catalog) searchArtists(query string) []*fixtureArtist {
+	var matches []*fixtureArtist
+	for _, artist := range c.artists {
+		if matchesQuery(query, artist.Name) {
+			matches = append(matches, artist)
+		}
+	}
+	return matches
+}
+
+// matchesQuery reports whether every word of the query appears in one of
+// the names, ignoring case.
+func matchesQuery(query string, names ...string) bool {
+	haystack := strings.ToLower(strings.Join(names, " "))
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return false
+	}
+	for _, word := range words {
+		if !strings.Contains(haystack, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// page returns the window of n items starting at offset.
+func page(n, offset, limit int) (start, end int) {
+	start = min(max(offset, 0), n)
+	end = n
+	if limit > 0 {
+		end = min(start+limit, n)
+	}
+	return start, end
+}
@@ -0,0 +1,595 @@
+package spotifytest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+
+	zspotify "github.com/zmb3/spotify/v2"
+)
+
+// Tokens issued by the stand-in token endpoint. The user token is only
+// handed out for the refresh_token grant, and only it may call /v1/me.
+const (
+	AppToken  = "spotifytest-app-token"
+	UserToken = "spotifytest-user-token"
+)
+
+// Server is an httptest stand-in for the Spotify Web API and Accounts token
+// endpoint, serving the fixture catalog.
+type Server struct {
+	*httptest.Server
+}
+
+// NewServer starts a stand-in server. Callers must Close it.
+func NewServer() *Server {
+	return &Server{Server: httptest.NewServer(Handler())}
+}
+
+// Transport returns a RoundTripper that sends requests meant for
+// api.spotify.com or accounts.spotify.com to the stand-in instead, so a
+// real client can be used unchanged:
+//
+//	spotify.NewClientWithTransport(cfg, srv.Transport())
+func (s *Server) Transport() http.RoundTripper {
+	target, _ := url.Parse(s.URL)
+	return &rewriteTransport{target: target, next: s.Client().Transport}
+}
+
+type rewriteTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	req.Host = t.target.Host
+	return t.next.RoundTrip(req)
+}
+
+// Handler returns the stand-in as a plain http.Handler.
+func Handler() http.Handler {
+	h := &handler{catalog: mustLoadCatalog()}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/token", h.token)
+	mux.HandleFunc("GET /v1/search", h.authorized(h.search))
+	mux.HandleFunc("GET /v1/tracks", h.authorized(h.tracks))
+	mux.HandleFunc("GET /v1/tracks/{id}", h.authorized(h.track))
+	mux.HandleFunc("GET /v1/artists/{id}", h.authorized(h.artist))
+	mux.HandleFunc("GET /v1/albums/{id}", h.authorized(h.album))
+	mux.HandleFunc("GET /v1/playlists/{id}", h.authorized(h.playlist))
+	mux.HandleFunc("GET /v1/playlists/{id}/tracks", h.authorized(h.playlistItems))
+	mux.HandleFunc("POST /v1/playlists/{id}/tracks", h.authorized(h.addPlaylistItems))
+	mux.HandleFunc("DELETE /v1/playlists/{id}/tracks", h.authorized(h.removePlaylistItems))
+	mux.HandleFunc("PUT /v1/playlists/{id}/tracks", h.authorized(h.replacePlaylistItems))
+	mux.HandleFunc("GET /v1/browse/categories", h.authorized(h.categories))
+	mux.HandleFunc("GET /v1/browse/categories/{id}/playlists", h.authorized(h.categoryPlaylists))
+	mux.HandleFunc("GET /v1/users/{id}", h.authorized(h.user))
+	mux.HandleFunc("GET /v1/me", h.authorized(h.me))
+	mux.HandleFunc("GET /v1/me/playlists", h.authorized(h.myPlaylists))
+	mux.HandleFunc("GET /v1/me/top/{type}", h.authorized(h.topItems))
+	mux.HandleFunc("GET /v1/me/player/devices", h.authorized(h.devices))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "Service not found")
+	})
+	return mux
+}
+
+type handler struct {
+	catalog *catalog
+}
+
+func (h *handler) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, "invalid_request")
+		return
+	}
+	if _, _, ok := r.BasicAuth(); !ok && r.PostForm.Get("client_id") == "" {
+		writeTokenError(w, "invalid_client")
+		return
+	}
+
+	token := map[string]interface{}{
+		"token_type": "Bearer",
+		"expires_in": 3600,
+	}
+	switch r.PostForm.Get("grant_type") {
+	case "client_credentials":
+		token["access_token"] = AppToken
+	case "refresh_token":
+		token["access_token"] = UserToken
+		token["refresh_token"] = r.PostForm.Get("refresh_token")
+	default:
+		writeTokenError(w, "unsupported_grant_type")
+		return
+	}
+	writeJSON(w, r, token)
+}
+
+// authorized rejects requests without a token issued by the stand-in.
+func (h *handler) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		case AppToken, UserToken:
+			next(w, r)
+		default:
+			writeError(w, http.StatusUnauthorized, "No token provided")
+		}
+	}
+}
+
+func (h *handler) search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	offset, limit := paging(query, 20)
+	market := query.Get("market")
+
+	result := map[string]interface{}{}
+	for _, searchType := range strings.Split(query.Get("type"), ",") {
+		switch searchType {
+		case "track":
+			matches := h.catalog.searchTracks(query.Get("q"))
+			start, end := page(len(matches), offset, limit)
+			items := make([]json.RawMessage, 0, end-start)
+			for _, track := range matches[start:end] {
+				items = append(items, trackJSON(track, market))
+			}
+			result["tracks"] = pageJSON(items, len(matches), start, limit)
+		case "artist":
+			matches := h.catalog.searchArtists(query.Get("q"))
+			start, end := page(len(matches), offset, limit)
+			items := make([]json.RawMessage, 0, end-start)
+			for _, artist := range matches[start:end] {
+				items = append(items, artist.raw)
+			}
+			result["artists"] = pageJSON(items, len(matches), start, limit)
+		default:
+			writeError(w, http.StatusBadRequest, "Bad search type field")
+			return
+		}
+	}
+	writeJSON(w, r, result)
+}
+
+func (h *handler) tracks(w http.ResponseWriter, r *http.Request) {
+	market := r.URL.Query().Get("market")
+	ids := strings.Split(r.URL.Query().Get("ids"), ",")
+	if len(ids) > 50 {
+		writeError(w, http.StatusBadRequest, "Too many ids requested")
+		return
+	}
+
+	// Unknown IDs are reported positionally as null
+	tracks := make([]json.RawMessage, len(ids))
+	for i, id := range ids {
+		tracks[i] = json.RawMessage("null")
+		if track := h.catalog.track(id); track != nil {
+			tracks[i] = trackJSON(track, market)
+		}
+	}
+	writeJSON(w, r, map[string]interface{}{"tracks": tracks})
+}
+
+func (h *handler) track(w http.ResponseWriter, r *http.Request) {
+	track := h.catalog.track(r.PathValue("id"))
+	if track == nil {
+		writeError(w, http.StatusNotFound, "Non existing id")
+		return
+	}
+	writeJSON(w, r, trackJSON(track, r.URL.Query().Get("market")))
+}
+
+func (h *handler) artist(w http.ResponseWriter, r *http.Request) {
+	artist := h.catalog.artist(r.PathValue("id"))
+	if artist == nil {
+		writeError(w, http.StatusNotFound, "Non existing id")
+		return
+	}
+	writeJSON(w, r, artist.raw)
+}
+
+func (h *handler) album(w http.ResponseWriter, r *http.Request) {
+	album := h.catalog.album(r.PathValue("id"))
+	if album == nil {
+		writeError(w, http.StatusNotFound, "Non existing id")
+		return
+	}
+	if r.URL.Query().Get("market") != "" {
+		writeJSON(w, r, album.raw)
+		return
+	}
+
+	// Strip playability from the embedded tracks, as trackJSON does
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(album.raw, &fields); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var tracks struct {
+		Items []json.RawMessage `json:"items"`
+		Total int               `json:"total"`
+	}
+	if err := json.Unmarshal(fields["tracks"], &tracks); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i, item := range tracks.Items {
+		tracks.Items[i] = withoutPlayability(item)
+	}
+	data, err := json.Marshal(tracks)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	fields["tracks"] = data
+	writeJSON(w, r, fields)
+}
+
+// playlist returns the full playlist object with every fixture item on the
+// first page.
+func (h *handler) playlist(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	playlist := h.catalog.playlist(id)
+	if playlist == nil {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(playlist.raw, &fields); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	tracks, err := json.Marshal(h.playlistPage(playlist, r.URL.Query().Get("market"), 0, 100))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	fields["tracks"] = tracks
+	snapshotID, err := json.Marshal(h.catalog.snapshotID(id))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	fields["snapshot_id"] = snapshotID
+	writeJSON(w, r, fields)
+}
+
+func (h *handler) playlistItems(w http.ResponseWriter, r *http.Request) {
+	playlist := h.catalog.playlist(r.PathValue("id"))
+	if playlist == nil {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	query := r.URL.Query()
+	offset, limit := paging(query, 100)
+	if limit > 100 {
+		writeError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+	writeJSON(w, r, h.playlistPage(playlist, query.Get("market"), offset, limit))
+}
+
+// playlistPage returns a page of a playlist's items. The total is the
+// fixture's, which may exceed the items the fixtures hold.
+func (h *handler) playlistPage(playlist *fixturePlaylist, market string, offset, limit int) map[string]interface{} {
+	tracks := h.catalog.playlistTracks(playlist.ID)
+	start, end := page(len(tracks), offset, limit)
+
+	items := []map[string]interface{}{}
+	for _, track := range tracks[start:end] {
+		var data json.RawMessage
+		if track != nil {
+			data = trackJSON(track, market)
+		}
+		items = append(items, map[string]interface{}{
+			"is_local": false,
+			"track":    data,
+		})
+	}
+	return map[string]interface{}{
+		"items":  items,
+		"total":  h.catalog.playlistTotal(playlist),
+		"offset": start,
+		"limit":  limit,
+	}
+}
+
+func (h *handler) addPlaylistItems(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		URIs     []string `json:"uris"`
+		Position *int     `json:"position"`
+	}
+	h.editPlaylist(w, r, &body, func(id string) (string, error) {
+		return h.catalog.addItems(id, body.URIs, body.Position)
+	}, http.StatusCreated)
+}
+
+// removePlaylistItems removes every occurrence of the tracks, or only
+// those at the tracks' positions when they have some.
+func (h *handler) removePlaylistItems(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Tracks []struct {
+			URI       string `json:"uri"`
+			Positions []int  `json:"positions"`
+		} `json:"tracks"`
+	}
+	h.editPlaylist(w, r, &body, func(id string) (string, error) {
+		var uris, positioned []string
+		var positions []int
+		for _, track := range body.Tracks {
+			if len(track.Positions) == 0 {
+				uris = append(uris, track.URI)
+			}
+			for _, position := range track.Positions {
+				positioned = append(positioned, track.URI)
+				positions = append(positions, position)
+			}
+		}
+		switch {
+		case len(positions) == 0:
+			return h.catalog.removeItems(id, uris)
+		case len(uris) > 0:
+			return "", spotifyError(400, "Could not remove tracks, please check parameters.")
+		}
+		return h.catalog.removeItemsAt(id, positioned, positions)
+	}, http.StatusOK)
+}
+
+// replacePlaylistItems also serves reorders, which Spotify sends to the
+// same endpoint with a range instead of URIs.
+func (h *handler) replacePlaylistItems(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		URIs         []string `json:"uris"`
+		RangeStart   *int     `json:"range_start"`
+		RangeLength  *int     `json:"range_length"`
+		InsertBefore *int     `json:"insert_before"`
+	}
+	h.editPlaylist(w, r, &body, func(id string) (string, error) {
+		if body.RangeStart == nil {
+			return h.catalog.replaceItems(id, body.URIs)
+		}
+		if body.InsertBefore == nil {
+			return "", spotifyError(400, "Missing insert_before")
+		}
+		length := 1
+		if body.RangeLength != nil {
+			length = *body.RangeLength
+		}
+		return h.catalog.reorderItems(id, *body.RangeStart, length, *body.InsertBefore)
+	}, http.StatusOK)
+}
+
+// editPlaylist decodes an edit's JSON body into body, applies it with edit
+// and answers with the new snapshot ID.
+func (h *handler) editPlaylist(w http.ResponseWriter, r *http.Request, body interface{}, edit func(id string) (string, error), status int) {
+	if !userToken(w, r) {
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		writeError(w, http.StatusBadRequest, "Error parsing JSON.")
+		return
+	}
+
+	snapshotID, err := edit(r.PathValue("id"))
+	if err != nil {
+		var e zspotify.Error
+		if !errors.As(err, &e) {
+			e = spotifyError(http.StatusInternalServerError, err.Error())
+		}
+		writeError(w, e.Status, e.Message)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"snapshot_id": snapshotID})
+}
+
+func (h *handler) categories(w http.ResponseWriter, r *http.Request) {
+	offset, limit := paging(r.URL.Query(), 20)
+	start, end := page(len(h.catalog.categories), offset, limit)
+
+	items := make([]json.RawMessage, 0, end-start)
+	for _, category := range h.catalog.categories[start:end] {
+		items = append(items, category.raw)
+	}
+	writeJSON(w, r, map[string]interface{}{
+		"categories": pageJSON(items, len(h.catalog.categories), start, limit),
+	})
+}
+
+func (h *handler) categoryPlaylists(w http.ResponseWriter, r *http.Request) {
+	playlists, ok := h.catalog.playlists[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Specified id doesn't exist")
+		return
+	}
+
+	offset, limit := paging(r.URL.Query(), 20)
+	start, end := page(len(playlists), offset, limit)
+
+	items := make([]json.RawMessage, 0, end-start)
+	for _, playlist := range playlists[start:end] {
+		if playlist == nil {
+			items = append(items, json.RawMessage("null"))
+			continue
+		}
+		items = append(items, playlist.raw)
+	}
+	writeJSON(w, r, map[string]interface{}{
+		"playlists": pageJSON(items, len(playlists), start, limit),
+	})
+}
+
+func (h *handler) user(w http.ResponseWriter, r *http.Request) {
+	user := h.catalog.user(r.PathValue("id"))
+	if user == nil {
+		writeError(w, http.StatusNotFound, "No such user")
+		return
+	}
+	writeJSON(w, r, user.raw)
+}
+
+func (h *handler) me(w http.ResponseWriter, r *http.Request) {
+	if !userToken(w, r) {
+		return
+	}
+	writeJSON(w, r, h.catalog.me.raw)
+}
+
+func (h *handler) myPlaylists(w http.ResponseWriter, r *http.Request) {
+	if !userToken(w, r) {
+		return
+	}
+
+	offset, limit := paging(r.URL.Query(), 20)
+	start, end := page(len(h.catalog.myPlaylists), offset, limit)
+
+	items := make([]json.RawMessage, 0, end-start)
+	for _, playlist := range h.catalog.myPlaylists[start:end] {
+		items = append(items, playlist.raw)
+	}
+	writeJSON(w, r, pageJSON(items, len(h.catalog.myPlaylists), start, limit))
+}
+
+// topItems serves the catalog's artists or tracks in fixture order as the
+// user's top items, whatever the time range.
+func (h *handler) topItems(w http.ResponseWriter, r *http.Request) {
+	if !userToken(w, r) {
+		return
+	}
+
+	var items []json.RawMessage
+	switch r.PathValue("type") {
+	case "artists":
+		for _, artist := range h.catalog.artists {
+			items = append(items, artist.raw)
+		}
+	case "tracks":
+		for _, track := range h.catalog.tracks {
+			items = append(items, trackJSON(track, ""))
+		}
+	default:
+		writeError(w, http.StatusNotFound, "Service not found")
+		return
+	}
+
+	offset, limit := paging(r.URL.Query(), 20)
+	start, end := page(len(items), offset, limit)
+	writeJSON(w, r, pageJSON(items[start:end], len(items), start, limit))
+}
+
+func (h *handler) devices(w http.ResponseWriter, r *http.Request) {
+	if !userToken(w, r) {
+		return
+	}
+
+	devices := make([]json.RawMessage, len(h.catalog.devices))
+	for i, device := range h.catalog.devices {
+		devices[i] = device.raw
+	}
+	writeJSON(w, r, map[string]interface{}{"devices": devices})
+}
+
+// userToken rejects requests made with the app-only token, as Spotify does
+// for endpoints acting on behalf of a user.
+func userToken(w http.ResponseWriter, r *http.Request) bool {
+	if strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ") != UserToken {
+		writeError(w, http.StatusUnauthorized, "Valid user authentication required")
+		return false
+	}
+	return true
+}
+
+// trackJSON returns the track as Spotify would for the market: playability
+// fields are only present when a market is given.
+func trackJSON(track *fixtureTrack, market string) json.RawMessage {
+	if market != "" {
+		return track.raw
+	}
+	return withoutPlayability(track.raw)
+}
+
+func withoutPlayability(raw json.RawMessage) json.RawMessage {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return raw
+	}
+	delete(fields, "is_playable")
+	delete(fields, "restrictions")
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return raw
+	}
+	return data
+}
+
+func pageJSON(items []json.RawMessage, total, offset, limit int) map[string]interface{} {
+	return map[string]interface{}{
+		"items":  items,
+		"total":  total,
+		"offset": offset,
+		"limit":  limit,
+	}
+}
+
+func paging(query url.Values, defaultLimit int) (offset, limit int) {
+	limit = defaultLimit
+	if n, err := strconv.Atoi(query.Get("limit")); err == nil && n > 0 {
+		limit = n
+	}
+	if n, err := strconv.Atoi(query.Get("offset")); err == nil && n > 0 {
+		offset = n
+	}
+	return offset, limit
+}
+
+// writeJSON writes v with an ETag, answering 304 when the client already
+// has the same representation.
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": spotifyError(status, message),
+	})
+}
+
+func writeTokenError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+func spotifyError(status int, message string) zspotify.Error {
+	return zspotify.Error{Status: status, Message: message}
+}
@@ -0,0 +1,165 @@
+package spotify
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxRetries   = 4
+	defaultMaxRetryWait = 20 * time.Second
+
+	backoffBase = 500 * time.Millisecond
+	backoffCap  = 8 * time.Second
+)
+
+// RateLimitError is returned when Spotify keeps answering 429 and the
+// retry budget is spent. RetryAfter is how long the caller should wait
+// before trying again.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited by Spotify; retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+// UpstreamError is returned when Spotify keeps failing a request with a 5xx
+// and the retry budget is spent.
+type UpstreamError struct {
+	StatusCode int
+	Attempts   int
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("Spotify returned HTTP %d (%d attempts)", e.StatusCode, e.Attempts)
+}
+
+// retryTransport retries requests that Spotify rejected with 429, honoring
+// Retry-After, and idempotent requests that failed with a 5xx, using
+// jittered exponential backoff. The total time spent waiting on a single
+// request is capped by maxWait. A maxRetries of 0 disables retries.
+type retryTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+	maxWait    time.Duration
+}
+
+func newRetryTransport(next http.RoundTripper, maxRetries int, maxWait time.Duration) *retryTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if maxRetries < 0 {
+		maxRetries = defaultMaxRetries
+	}
+	if maxWait <= 0 {
+		maxWait = defaultMaxRetryWait
+	}
+	return &retryTransport{
+		next:       next,
+		maxRetries: maxRetries,
+		maxWait:    maxWait,
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	var waited time.Duration
+
+	for attempt := 0; ; attempt++ {
+		attemptReq, err := rewindRequest(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := t.next.RoundTrip(attemptReq)
+		if err != nil {
+			return nil, err
+		}
+
+		var wait time.Duration
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			wait = retryAfter(resp, attempt)
+		case resp.StatusCode >= 500 && isIdempotent(req.Method):
+			wait = backoff(attempt)
+		default:
+			return resp, nil
+		}
+
+		// Give up when out of attempts, when the wait would exceed the
+		// budget, or when the caller's deadline would pass first.
+		deadline, hasDeadline := ctx.Deadline()
+		if attempt >= t.maxRetries || waited+wait > t.maxWait ||
+			(hasDeadline && time.Now().Add(wait).After(deadline)) {
+			drain(resp)
+			if resp.StatusCode == http.StatusTooManyRequests {
+				return nil, &RateLimitError{RetryAfter: wait}
+			}
+			return nil, &UpstreamError{StatusCode: resp.StatusCode, Attempts: attempt + 1}
+		}
+		drain(resp)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		waited += wait
+	}
+}
+
+// rewindRequest returns the request to send for the given attempt, with a
+// fresh body for retries.
+func rewindRequest(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("cannot retry %s %s: request body is not replayable", req.Method, req.URL.Path)
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = body
+	return clone, nil
+}
+
+func retryAfter(resp *http.Response, attempt int) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return backoff(attempt)
+}
+
+// backoff returns a "full jitter" delay: a random duration up to an
+// exponentially growing ceiling.
+func backoff(attempt int) time.Duration {
+	ceiling := backoffBase << attempt
+	if ceiling > backoffCap || ceiling <= 0 {
+		ceiling = backoffCap
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func drain(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
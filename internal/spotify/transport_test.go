@@ -0,0 +1,89 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// stubTransport answers requests with the given statuses in turn, the
+// last one repeatedly, and counts the attempts.
+type stubTransport struct {
+	statuses []int
+	attempts int
+	err      error
+}
+
+func (t *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status := t.statuses[min(t.attempts, len(t.statuses)-1)]
+	t.attempts++
+	if t.err != nil {
+		return nil, t.err
+	}
+	header := http.Header{}
+	if status == http.StatusTooManyRequests {
+		header.Set("Retry-After", "0")
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader("{}")),
+		Request:    req,
+	}, nil
+}
+
+func newTestRequest(t *testing.T, ctx context.Context, method string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, method, "https://api.spotify.com/v1/me", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		statuses   []int
+		maxRetries int
+		attempts   int
+		status     int
+		err        error
+	}{
+		{"recovers", http.MethodGet, []int{503, 200}, 2, 2, 200, nil},
+		{"retries disabled", http.MethodGet, []int{503, 200}, 0, 1, 0, &UpstreamError{StatusCode: 503, Attempts: 1}},
+		{"keeps failing", http.MethodGet, []int{502}, 2, 3, 0, &UpstreamError{StatusCode: 502, Attempts: 3}},
+		{"rate limited", http.MethodGet, []int{429}, 1, 2, 0, &RateLimitError{}},
+		{"not idempotent", http.MethodPost, []int{503, 200}, 2, 1, 503, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := &stubTransport{statuses: test.statuses}
+			resp, err := newRetryTransport(stub, test.maxRetries, 0).RoundTrip(newTestRequest(t, context.Background(), test.method))
+			if stub.attempts != test.attempts {
+				t.Errorf("got %d attempts, want %d", stub.attempts, test.attempts)
+			}
+			switch want := test.err.(type) {
+			case nil:
+				if err != nil || resp.StatusCode != test.status {
+					t.Fatalf("got %v, %v; want status %d", resp, err, test.status)
+				}
+				resp.Body.Close()
+			case *UpstreamError:
+				var upstreamErr *UpstreamError
+				if !errors.As(err, &upstreamErr) || *upstreamErr != *want {
+					t.Errorf("got %v, want %v", err, want)
+				}
+			case *RateLimitError:
+				var rateErr *RateLimitError
+				if !errors.As(err, &rateErr) {
+					t.Errorf("got %v, want a rate limit error", err)
+				}
+			}
+		})
+	}
+}
@@ -6,6 +6,10 @@ type Track struct {
 	Artist string `json:"artist"`
 	Album  string `json:"album"`
 	URI    string `json:"uri"`
+	// Playability fields are only reported when a market is known
+	IsPlayable  *bool  `json:"is_playable,omitempty"`
+	Restriction string `json:"restriction,omitempty"`
+	LinkedFrom  string `json:"linked_from,omitempty"`
 }
 
 type Artist struct {
@@ -13,6 +17,19 @@ type Artist struct {
 	Name       string `json:"name"`
 	Popularity int    `json:"popularity"`
 	URI        string `json:"uri"`
+	// Only reported when fetching a single artist
+	Genres    []string `json:"genres,omitempty"`
+	Followers int      `json:"followers,omitempty"`
+}
+
+type Album struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Artist      string  `json:"artist"`
+	ReleaseDate string  `json:"release_date,omitempty"`
+	TotalTracks int     `json:"total_tracks"`
+	Tracks      []Track `json:"tracks"`
+	URI         string  `json:"uri"`
 }
 
 type SearchResult struct {
@@ -24,3 +41,95 @@ type ArtistSearchResult struct {
 	Artists []Artist `json:"artists"`
 	Total   int      `json:"total"`
 }
+
+type Category struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type CategoryPage struct {
+	Categories []Category `json:"categories"`
+	Total      int        `json:"total"`
+	Limit      int        `json:"limit"`
+	Offset     int        `json:"offset"`
+}
+
+type Playlist struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Owner       string `json:"owner"`
+	TrackCount  int    `json:"track_count"`
+	URI         string `json:"uri"`
+	// SnapshotID identifies the version of the playlist's contents
+	SnapshotID string `json:"snapshot_id,omitempty"`
+	// Tracks holds the first page of tracks when fetching a single playlist
+	Tracks []Track `json:"tracks,omitempty"`
+}
+
+// PlaylistItems is the complete, ordered contents of a playlist at one
+// snapshot. Items that are no longer available on Spotify are left out.
+type PlaylistItems struct {
+	PlaylistID string `json:"playlist_id"`
+	Name       string `json:"name"`
+	SnapshotID string `json:"snapshot_id"`
+	// Items are in playlist order. Tracks Spotify no longer has are kept
+	// as items without a URI, so indices match Spotify's.
+	Items []Track `json:"items"`
+}
+
+// PlaylistItemPosition is a playlist item at an index.
+type PlaylistItemPosition struct {
+	URI      string
+	Position int
+}
+
+// TopItems are the artists and tracks the authorized user played most over
+// a time range.
+type TopItems struct {
+	TimeRange string   `json:"time_range"`
+	Artists   []Artist `json:"artists"`
+	Tracks    []Track  `json:"tracks"`
+}
+
+type PlaylistPage struct {
+	Playlists []Playlist `json:"playlists"`
+	Total     int        `json:"total"`
+	Limit     int        `json:"limit"`
+	Offset    int        `json:"offset"`
+}
+
+type Device struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	IsActive   bool   `json:"is_active"`
+	Restricted bool   `json:"is_restricted,omitempty"`
+	Volume     int    `json:"volume_percent"`
+}
+
+type User struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+	Followers   int    `json:"followers"`
+	URI         string `json:"uri"`
+}
+
+type ExplicitContent struct {
+	FilterEnabled bool `json:"filter_enabled"`
+	FilterLocked  bool `json:"filter_locked"`
+}
+
+type CurrentUser struct {
+	User
+	Email           string          `json:"email,omitempty"`
+	Country         string          `json:"country,omitempty"`
+	Product         string          `json:"product,omitempty"`
+	ExplicitContent ExplicitContent `json:"explicit_content"`
+}
+
+// IsPremium reports whether the user has a Premium subscription, which
+// Spotify requires for playback control.
+func (u *CurrentUser) IsPremium() bool {
+	return u.Product == "premium"
+}
@@ -0,0 +1,196 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// ErrUserAuthRequired is returned by endpoints that act on behalf of a user
+// when the client only has app-only credentials.
+var ErrUserAuthRequired = errors.New("this operation requires user authorization; set SPOTIFY_REFRESH_TOKEN")
+
+// UserAuthorized reports whether the client holds a user token.
+func (c *Client) UserAuthorized() bool {
+	return c.userAuthorized
+}
+
+// GetCurrentUser returns the profile of the authorized user. The profile is
+// fetched once and reused, since market and subscription rarely change.
+func (c *Client) GetCurrentUser(ctx context.Context) (*CurrentUser, error) {
+	if !c.userAuthorized {
+		return nil, ErrUserAuthRequired
+	}
+
+	c.userMu.Lock()
+	defer c.userMu.Unlock()
+
+	if c.currentUser != nil {
+		return c.currentUser, nil
+	}
+
+	// The library's PrivateUser omits explicit content settings, so decode
+	// the response ourselves.
+	var profile struct {
+		spotify.PrivateUser
+		ExplicitContent ExplicitContent `json:"explicit_content"`
+	}
+	if err := c.getJSON(ctx, "me", nil, &profile); err != nil {
+		return nil, fmt.Errorf("failed to get current user: %w", err)
+	}
+
+	c.currentUser = &CurrentUser{
+		User:            newUser(profile.User),
+		Email:           profile.Email,
+		Country:         profile.Country,
+		Product:         profile.Product,
+		ExplicitContent: profile.ExplicitContent,
+	}
+	return c.currentUser, nil
+}
+
+func (c *Client) GetUserProfile(ctx context.Context, userID string) (*User, error) {
+	return coalesce(ctx, &c.flight, flightKey("GetUserProfile", userID), func(ctx context.Context) (*User, error) {
+		return c.getUserProfile(ctx, userID)
+	})
+}
+
+func (c *Client) getUserProfile(ctx context.Context, userID string) (*User, error) {
+	user, err := c.client.GetUsersPublicProfile(ctx, spotify.ID(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user profile: %w", err)
+	}
+
+	profile := newUser(*user)
+	return &profile, nil
+}
+
+// UserMarket returns the country of the authorized user, or an empty string
+// when it is unknown.
+func (c *Client) UserMarket(ctx context.Context) string {
+	if !c.userAuthorized {
+		return ""
+	}
+	user, err := c.GetCurrentUser(ctx)
+	if err != nil {
+		return ""
+	}
+	return user.Country
+}
+
+func newUser(user spotify.User) User {
+	return User{
+		ID:          user.ID,
+		DisplayName: user.DisplayName,
+		Followers:   int(user.Followers.Count),
+		URI:         string(user.URI),
+	}
+}
+
+// GetMyPlaylists returns a page of the playlists the authorized user owns or
+// follows.
+func (c *Client) GetMyPlaylists(ctx context.Context, limit, offset int) (*PlaylistPage, error) {
+	if !c.userAuthorized {
+		return nil, ErrUserAuthRequired
+	}
+
+	key := flightKey("GetMyPlaylists", limit, offset)
+	return coalesce(ctx, &c.flight, key, func(ctx context.Context) (*PlaylistPage, error) {
+		return c.getMyPlaylists(ctx, limit, offset)
+	})
+}
+
+func (c *Client) getMyPlaylists(ctx context.Context, limit, offset int) (*PlaylistPage, error) {
+	opts := BrowseOptions{Limit: limit, Offset: offset}
+	page, err := c.client.CurrentUsersPlaylists(ctx, opts.requestOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playlists: %w", err)
+	}
+
+	playlists := make([]Playlist, 0, len(page.Playlists))
+	for _, playlist := range page.Playlists {
+		if playlist.ID == "" {
+			continue
+		}
+		playlists = append(playlists, newPlaylist(playlist))
+	}
+
+	return &PlaylistPage{
+		Playlists: playlists,
+		Total:     int(page.Total),
+		Limit:     int(page.Limit),
+		Offset:    int(page.Offset),
+	}, nil
+}
+
+// Time ranges for GetTopItems: about the last four weeks, six months and
+// year.
+const (
+	TimeRangeShort  = "short_term"
+	TimeRangeMedium = "medium_term"
+	TimeRangeLong   = "long_term"
+)
+
+// TimeRanges lists the time ranges GetTopItems accepts.
+var TimeRanges = []string{TimeRangeShort, TimeRangeMedium, TimeRangeLong}
+
+// GetTopItems returns the authorized user's most played artists and tracks
+// over timeRange, up to limit of each.
+func (c *Client) GetTopItems(ctx context.Context, timeRange string, limit int) (*TopItems, error) {
+	if !c.userAuthorized {
+		return nil, ErrUserAuthRequired
+	}
+	if !slices.Contains(TimeRanges, timeRange) {
+		return nil, fmt.Errorf("unknown time range %q, expected one of %v", timeRange, TimeRanges)
+	}
+
+	key := flightKey("GetTopItems", timeRange, limit)
+	return coalesce(ctx, &c.flight, key, func(ctx context.Context) (*TopItems, error) {
+		return c.getTopItems(ctx, timeRange, limit)
+	})
+}
+
+func (c *Client) getTopItems(ctx context.Context, timeRange string, limit int) (*TopItems, error) {
+	params := url.Values{
+		"time_range": {timeRange},
+		"limit":      {strconv.Itoa(limit)},
+	}
+
+	var artists struct {
+		Items []spotify.FullArtist `json:"items"`
+	}
+	if err := c.getJSON(ctx, "me/top/artists", params, &artists); err != nil {
+		return nil, fmt.Errorf("failed to get top artists: %w", err)
+	}
+	var tracks struct {
+		Items []trackObject `json:"items"`
+	}
+	if err := c.getJSON(ctx, "me/top/tracks", params, &tracks); err != nil {
+		return nil, fmt.Errorf("failed to get top tracks: %w", err)
+	}
+
+	result := &TopItems{
+		TimeRange: timeRange,
+		Artists:   make([]Artist, len(artists.Items)),
+		Tracks:    make([]Track, len(tracks.Items)),
+	}
+	for i, artist := range artists.Items {
+		result.Artists[i] = Artist{
+			ID:         string(artist.ID),
+			Name:       artist.Name,
+			Popularity: int(artist.Popularity),
+			URI:        string(artist.URI),
+			Genres:     artist.Genres,
+			Followers:  int(artist.Followers.Count),
+		}
+	}
+	for i, track := range tracks.Items {
+		result.Tracks[i] = newTrack(track)
+	}
+	return result, nil
+}